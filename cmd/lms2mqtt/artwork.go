@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	log "github.com/sirupsen/logrus"
+)
+
+// Artwork modes
+const (
+	artworkNone  = "none"
+	artworkUrl   = "url"
+	artworkImage = "image"
+)
+
+func checkArtworkMode(mode string) error {
+	switch mode {
+	case artworkNone, artworkUrl, artworkImage:
+		return nil
+	default:
+		return fmt.Errorf("unknown artwork mode '%v', wants %v, %v or %v", mode, artworkNone, artworkUrl, artworkImage)
+	}
+}
+
+// publishTrack publish the track and, in image mode, its artwork when it changes
+func (a *application) publishTrack(t *squeeze.PlayerTrack) {
+	track := *t
+	if a.opts.artwork == artworkNone {
+		track.ArtworkUrl = ""
+	}
+	a.publishJson(trackTopic(a.topic, t.Player), true, track)
+	if a.opts.artwork == artworkImage {
+		a.publishArtwork(t.Player, t.ArtworkUrl)
+	}
+}
+
+// playerArtwork is the artwork state of a player, guarded by application.muArtworks
+type playerArtwork struct {
+	// url is the artwork of the current track
+	url string
+	// published is the artwork of the image on the art topic, when known
+	published string
+	known     bool
+	// loading is set while a goroutine downloads the artworks of the player
+	loading bool
+}
+
+// publishArtwork publish the image retained on the art topic, an empty payload clears the artwork.
+// Images are downloaded apart from the notifications loop, one player at a time and only the latest artwork
+// of each player is downloaded.
+func (a *application) publishArtwork(id squeeze.PlayerId, artworkUrl string) {
+	a.muArtworks.Lock()
+	defer a.muArtworks.Unlock()
+	if a.artworks == nil {
+		a.artworks = make(map[squeeze.PlayerId]*playerArtwork)
+	}
+	p, ok := a.artworks[id]
+	if !ok {
+		p = &playerArtwork{}
+		a.artworks[id] = p
+	}
+	p.url = artworkUrl
+	if p.loading || (p.known && p.published == artworkUrl) {
+		return
+	}
+	p.loading = true
+	a.pendingArtworks.Add(1)
+	go a.loadArtworks(id, p)
+}
+
+// loadArtworks publish the artwork of the player until the latest one is published
+func (a *application) loadArtworks(id squeeze.PlayerId, p *playerArtwork) {
+	defer a.pendingArtworks.Done()
+	for {
+		a.muArtworks.Lock()
+		artworkUrl := p.url
+		if p.known && p.published == artworkUrl {
+			p.loading = false
+			a.muArtworks.Unlock()
+			return
+		}
+		a.muArtworks.Unlock()
+
+		content := []byte{}
+		if artworkUrl != "" {
+			var err error
+			content, err = a.server.Artwork(artworkUrl)
+			if err != nil {
+				log.Errorf("unable to publish artwork of player %v: %v", id, err)
+				a.muArtworks.Lock()
+				if p.url == artworkUrl {
+					// Retry on next track notification
+					p.loading = false
+					a.muArtworks.Unlock()
+					return
+				}
+				a.muArtworks.Unlock()
+				continue
+			}
+		}
+		a.publish(artTopic(a.topic, id), true, content)
+
+		a.muArtworks.Lock()
+		p.published, p.known = artworkUrl, true
+		a.muArtworks.Unlock()
+	}
+}
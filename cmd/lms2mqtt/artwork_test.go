@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_publishTrack(t *testing.T) {
+	image := []byte{0xff, 0xd8, 0xff, 0xe0}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(image)
+	}))
+	defer ts.Close()
+	cover := ts.URL + "/music/a1/cover.jpg"
+
+	cases := []struct {
+		name               string
+		artwork            string
+		artworkUrl         string
+		expectedArtworkUrl string
+		expectedArt        []byte
+	}{
+		{"None", artworkNone, cover, "", nil},
+		{"Url", artworkUrl, cover, cover, nil},
+		{"Image", artworkImage, cover, cover, image},
+	}
+
+	for _, c := range cases {
+		client := &clientMock{}
+		app := application{
+			client: client,
+			params: &mqttTooling.MqttCliParameters{},
+			topic:  "lms",
+			opts:   options{artwork: c.artwork},
+			server: squeeze.NewJsonRpc(ts.URL),
+		}
+		app.publishTrack(&squeeze.PlayerTrack{Player: "p1", Track: squeeze.Track{Title: "Lucille", ArtworkUrl: c.artworkUrl}})
+		app.pendingArtworks.Wait()
+
+		p, ok := client.Published(trackTopic("lms", "p1"))
+		if !ok {
+			t.Errorf("[%v] no track published", c.name)
+			continue
+		}
+		if !p.retained {
+			t.Errorf("[%v] track should be retained for home assistant", c.name)
+		}
+		var track squeeze.PlayerTrack
+		if err := json.Unmarshal(p.payload, &track); err != nil {
+			t.Errorf("[%v] bad track payload %s: %v", c.name, p.payload, err)
+		}
+		if track.ArtworkUrl != c.expectedArtworkUrl {
+			t.Errorf("[%v] bad artwork url: %v, wants %v", c.name, track.ArtworkUrl, c.expectedArtworkUrl)
+		}
+
+		art, ok := client.Published(artTopic("lms", "p1"))
+		if ok != (c.expectedArt != nil) || !bytes.Equal(art.payload, c.expectedArt) {
+			t.Errorf("[%v] bad art publication: %#v", c.name, art)
+		}
+		if ok && !art.retained {
+			t.Errorf("[%v] art should be retained", c.name)
+		}
+	}
+}
+
+func Test_publishArtwork(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	client := &clientMock{}
+	app := application{
+		client: client,
+		params: &mqttTooling.MqttCliParameters{},
+		topic:  "lms",
+		opts:   options{artwork: artworkImage},
+		server: squeeze.NewJsonRpc(ts.URL),
+	}
+
+	cases := []struct {
+		name            string
+		artworkUrl      string
+		expectedPublish bool
+		expectedPayload string
+	}{
+		{"First artwork", ts.URL + "/a.jpg", true, "/a.jpg"},
+		{"Same artwork", ts.URL + "/a.jpg", false, ""},
+		{"New artwork", ts.URL + "/b.jpg", true, "/b.jpg"},
+		{"Missing artwork", ts.URL + "/missing.jpg", false, ""},
+		{"No artwork", "", true, ""},
+		{"Still no artwork", "", false, ""},
+	}
+	for _, c := range cases {
+		client.Reset()
+		app.publishArtwork("p1", c.artworkUrl)
+		app.pendingArtworks.Wait()
+		p, ok := client.Published(artTopic("lms", "p1"))
+		if ok != c.expectedPublish {
+			t.Errorf("[%v] bad publication: %#v", c.name, client.Publications())
+			continue
+		}
+		if ok && string(p.payload) != c.expectedPayload {
+			t.Errorf("[%v] bad payload: %s, wants %v", c.name, p.payload, c.expectedPayload)
+		}
+	}
+}
+
+func Test_publishArtworkSlowDownload(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.jpg" {
+			close(started)
+			<-release
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	client := &clientMock{}
+	app := application{
+		client: client,
+		params: &mqttTooling.MqttCliParameters{},
+		topic:  "lms",
+		opts:   options{artwork: artworkImage},
+		server: squeeze.NewJsonRpc(ts.URL),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		app.publishArtwork("p1", ts.URL+"/slow.jpg")
+		<-started
+		app.publishArtwork("p1", ts.URL+"/a.jpg")
+		app.publishArtwork("p1", ts.URL+"/b.jpg")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("artwork download shouldn't block the notifications")
+	}
+	close(release)
+	app.pendingArtworks.Wait()
+
+	var payloads []string
+	for _, p := range client.Publications() {
+		payloads = append(payloads, string(p.payload))
+	}
+	// Artworks notified while downloading are replaced by the latest one
+	if expected := []string{"/slow.jpg", "/b.jpg"}; !reflect.DeepEqual(payloads, expected) {
+		t.Errorf("bad publications: %#v, wants %#v", payloads, expected)
+	}
+}
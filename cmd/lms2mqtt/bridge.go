@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+)
+
+// bridge run the applications of all servers on a single mqtt connection.
+//
+// A connection has a single last will, it flags the bridge topic as offline; it is the status topic of the server
+// when a single server is bridged. Status topics of all servers are flagged offline when the bridge stops.
+type bridge struct {
+	client MQTT.Client
+	params *mqttTooling.MqttCliParameters
+	apps   []*application
+	// topic is the availability topic of the whole bridge
+	topic string
+}
+
+func (b *bridge) connect() error {
+	if b.client != nil && b.client.IsConnected() {
+		return fmt.Errorf("connection already exists")
+	}
+	if len(b.apps) == 0 {
+		return fmt.Errorf("no server to bridge")
+	}
+	client, err := connectMqtt(b.params, b.topic)
+	if err != nil {
+		return fmt.Errorf("unable to connect to mqtt bus: %v", err)
+	}
+	b.client = client
+	for _, app := range b.apps {
+		app.client = client
+	}
+	return nil
+}
+
+// Run listen all servers until they stop, a server which fails doesn't stop the others
+func (b *bridge) Run() error {
+	b.client.Publish(b.topic, byte(b.params.Qos), true, bridgeOnline)
+	errs := make(chan error, len(b.apps))
+	for _, app := range b.apps {
+		go func(app *application) {
+			err := app.Run()
+			// The other servers keep running, the status of this one mustn't stay online until the bridge stops
+			b.client.Publish(bridgeStatusTopic(app.topic), byte(b.params.Qos), true, bridgeOffline)
+			if err != nil {
+				err = fmt.Errorf("server %v stopped: %v", app.topic, err)
+			}
+			errs <- err
+		}(app)
+	}
+
+	var failure error
+	for range b.apps {
+		if err := <-errs; err != nil {
+			log.Error(err)
+			if failure == nil {
+				failure = err
+			}
+		}
+	}
+	return failure
+}
+
+func (b *bridge) Subscribe(topic string, onMessage MQTT.MessageHandler) error {
+	t := b.client.Subscribe(topic, byte(b.params.Qos), onMessage)
+	t.Wait()
+	return t.Error()
+}
+
+func (b *bridge) Stop() {
+	if b.client != nil && b.client.IsConnected() {
+		log.Info("Stop mqtt connection")
+		for _, app := range b.apps {
+			if topic := bridgeStatusTopic(app.topic); topic != b.topic {
+				b.client.Publish(topic, byte(b.params.Qos), true, bridgeOffline).Wait()
+			}
+		}
+		b.client.Publish(b.topic, byte(b.params.Qos), true, bridgeOffline).Wait()
+		b.client.Disconnect(50)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"testing"
+)
+
+func Test_bridgeStop(t *testing.T) {
+	client := clientMock{}
+	b := bridge{
+		client: &client,
+		params: &mqttTooling.MqttCliParameters{},
+		apps:   []*application{{topic: "lms/home"}, {topic: "lms/garage"}},
+		topic:  "lms2mqtt/bridge/status",
+	}
+
+	b.Stop()
+
+	for _, topic := range []string{"lms/home/bridge/status", "lms/garage/bridge/status", "lms2mqtt/bridge/status"} {
+		p, ok := client.Published(topic)
+		if !ok {
+			t.Errorf("%v should be published", topic)
+			continue
+		}
+		if string(p.payload) != bridgeOffline || !p.retained {
+			t.Errorf("bad %v publication: %v (retained %v)", topic, string(p.payload), p.retained)
+		}
+	}
+}
+
+func Test_bridgeRunStoppedServer(t *testing.T) {
+	client := clientMock{subscribeErr: fmt.Errorf("not authorized")}
+	params := &mqttTooling.MqttCliParameters{}
+	b := bridge{
+		client: &client,
+		params: params,
+		apps: []*application{
+			{client: &client, params: params, topic: "lms/home", server: squeeze.New("home:9090")},
+			{client: &client, params: params, topic: "lms/garage", server: squeeze.New("garage:9090")},
+		},
+		topic: "lms2mqtt/bridge/status",
+	}
+
+	if err := b.Run(); err == nil {
+		t.Errorf("stopped servers should fail")
+	}
+
+	for _, topic := range []string{"lms/home/bridge/status", "lms/garage/bridge/status"} {
+		p, ok := client.Published(topic)
+		if !ok || string(p.payload) != bridgeOffline || !p.retained {
+			t.Errorf("%v should be flagged offline: %#v", topic, p)
+		}
+	}
+	if p, ok := client.Published("lms2mqtt/bridge/status"); !ok || string(p.payload) != bridgeOnline {
+		t.Errorf("bridge should stay online until it stops: %#v", p)
+	}
+}
+
+func Test_bridgeConnectWithoutServer(t *testing.T) {
+	b := bridge{params: &mqttTooling.MqttCliParameters{}}
+	if err := b.connect(); err == nil {
+		t.Errorf("bridge without server shouldn't connect")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+type playerCommand func(s *squeeze.Server, id squeeze.PlayerId, args []string) error
+
+var playerCommands = map[string]playerCommand{
+	"play":     noArgs((*squeeze.Server).Play),
+	"pause":    noArgs((*squeeze.Server).Pause),
+	"toggle":   noArgs((*squeeze.Server).Toggle),
+	"stop":     noArgs((*squeeze.Server).Stop),
+	"next":     noArgs((*squeeze.Server).Next),
+	"previous": noArgs((*squeeze.Server).Previous),
+	"volume":   volumeCommand,
+	"mute":     noArgs((*squeeze.Server).Mute),
+	"unmute":   noArgs((*squeeze.Server).Unmute),
+	"power":    powerCommand,
+	"favorite": favoriteCommand,
+	"sync":     syncCommand,
+	"unsync":   noArgs((*squeeze.Server).Unsync),
+	"alarm":    alarmCommand,
+	"sleep":    sleepCommand,
+}
+
+func noArgs(cmd func(s *squeeze.Server, id squeeze.PlayerId) error) playerCommand {
+	return func(s *squeeze.Server, id squeeze.PlayerId, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("unexpected arguments %v", args)
+		}
+		return cmd(s, id)
+	}
+}
+
+// volumeCommand set the volume to an absolute value ("volume 40") or change it relatively ("volume +5")
+func volumeCommand(s *squeeze.Server, id squeeze.PlayerId, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("volume command needs exactly one argument, got %v", args)
+	}
+	value, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid volume value \"%v\": %v", args[0], err)
+	}
+	if strings.HasPrefix(args[0], "+") || strings.HasPrefix(args[0], "-") {
+		return s.StepVolume(id, value)
+	}
+	return s.SetVolume(id, value)
+}
+
+func powerCommand(s *squeeze.Server, id squeeze.PlayerId, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("power command needs exactly one argument, got %v", args)
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		return s.PowerOn(id)
+	case "off":
+		return s.PowerOff(id)
+	case "toggle":
+		return s.TogglePower(id)
+	default:
+		return fmt.Errorf("invalid power value \"%v\", must be 'on', 'off' or 'toggle'", args[0])
+	}
+}
+
+// favoriteCommand play a favorite designated by its id or its title ("favorite fip")
+func favoriteCommand(s *squeeze.Server, id squeeze.PlayerId, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("favorite command needs a favorite id or title")
+	}
+	return s.PlayFavorite(id, strings.Join(args, " "))
+}
+
+// syncCommand join the player to the group of another player ("sync 00:04:20:12:34:56")
+func syncCommand(s *squeeze.Server, id squeeze.PlayerId, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("sync command needs exactly one player id, got %v", args)
+	}
+	return s.Sync(id, squeeze.PlayerId(strings.ToLower(args[0])))
+}
+
+// sleepCommand set the sleep timer in seconds ("sleep 1800") or cancel it ("sleep cancel")
+func sleepCommand(s *squeeze.Server, id squeeze.PlayerId, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("sleep command needs exactly one argument, got %v", args)
+	}
+	if strings.ToLower(args[0]) == "cancel" {
+		return s.CancelSleep(id)
+	}
+	seconds, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid sleep time \"%v\": %v", args[0], err)
+	}
+	return s.SetSleep(id, seconds)
+}
+
+// alarmActions are the alarm actions which only take the alarm id
+var alarmActions = map[string]func(s *squeeze.Server, id squeeze.PlayerId, alarmId string) error{
+	"enable":  (*squeeze.Server).EnableAlarm,
+	"disable": (*squeeze.Server).DisableAlarm,
+	"delete":  (*squeeze.Server).DeleteAlarm,
+}
+
+// alarmCommand manage the player alarms:
+// "alarm add time:07:30 days:1,2,3,4,5 volume:40 repeat:1 playlist:<url>", "alarm update <id> time:08:00",
+// "alarm enable <id>", "alarm disable <id>" and "alarm delete <id>"
+func alarmCommand(s *squeeze.Server, id squeeze.PlayerId, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("alarm command needs an action")
+	}
+	action, args := strings.ToLower(args[0]), args[1:]
+	if action == "add" {
+		settings, err := alarmSettings(args)
+		if err != nil {
+			return err
+		}
+		return s.AddAlarm(id, settings)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("alarm %v command needs an alarm id", action)
+	}
+	alarmId, args := args[0], args[1:]
+	if action == "update" {
+		settings, err := alarmSettings(args)
+		if err != nil {
+			return err
+		}
+		return s.UpdateAlarm(id, alarmId, settings)
+	}
+	cmd, ok := alarmActions[action]
+	if !ok {
+		return fmt.Errorf("unknown alarm action \"%v\"", action)
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+	return cmd(s, id, alarmId)
+}
+
+// alarmSettings read 'name:value' arguments, names are case insensitive
+func alarmSettings(args []string) (map[string]string, error) {
+	settings := make(map[string]string, len(args))
+	for _, arg := range args {
+		i := strings.Index(arg, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid alarm setting \"%v\", must be name:value", arg)
+		}
+		settings[strings.ToLower(arg[:i])] = arg[i+1:]
+	}
+	return settings, nil
+}
+
+func (a *application) onCommand(_ MQTT.Client, message MQTT.Message) {
+	id, err := playerFromTopic(a.topic, "cmd", message.Topic())
+	if err != nil {
+		log.Warnf("unable to handle command: %v", err)
+		return
+	}
+
+	// Only the command name is case insensitive, arguments like urls are kept as is
+	fields := strings.Fields(string(message.Payload()))
+	if len(fields) == 0 {
+		log.Warnf("empty command for player %v", id)
+		return
+	}
+	name, args := strings.ToLower(fields[0]), fields[1:]
+	cmd, ok := playerCommands[name]
+	if !ok {
+		log.Warnf("unknown command %#v for player %v", name, id)
+		return
+	}
+
+	log.Infof("send command %v %v to player %v", name, args, id)
+	if err := cmd(a.server, id, args); err != nil {
+		log.Errorf("unable to run command %v on player %v: %v", name, id, err)
+	}
+}
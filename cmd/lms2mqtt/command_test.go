@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_onCommand(t *testing.T) {
+	cases := []struct {
+		name             string
+		topic            string
+		payload          string
+		expectedCommands []string
+	}{
+		{"Play", "lms/player/cmd", "play", []string{"player play"}},
+		{"Pause", "lms/player/cmd", "pause", []string{"player pause 1"}},
+		{"Toggle", "lms/player/cmd", "toggle", []string{"player pause"}},
+		{"Stop", "lms/player/cmd", " STOP\n", []string{"player stop"}},
+		{"Next", "lms/player/cmd", "next", []string{"player playlist index +1"}},
+		{"Previous", "lms/player/cmd", "previous", []string{"player playlist index -1"}},
+		{"Set volume", "lms/player/cmd", "volume 40", []string{"player mixer volume 40"}},
+		{"Volume up", "lms/player/cmd", "volume +5", []string{"player mixer volume +5"}},
+		{"Volume down", "lms/player/cmd", "volume -5", []string{"player mixer volume -5"}},
+		{"Invalid volume", "lms/player/cmd", "volume loud", []string{}},
+		{"Missing volume", "lms/player/cmd", "volume", []string{}},
+		{"Mute", "lms/player/cmd", "mute", []string{"player mixer muting 1"}},
+		{"Unmute", "lms/player/cmd", "unmute", []string{"player mixer muting 0"}},
+		{"Power on", "lms/player/cmd", "power on", []string{"player power 1"}},
+		{"Power off", "lms/player/cmd", "power OFF", []string{"player power 0"}},
+		{"Toggle power", "lms/player/cmd", "power toggle", []string{"player power"}},
+		{"Invalid power", "lms/player/cmd", "power 1", []string{}},
+		{"Unknown favorite", "lms/player/cmd", "favorite FIP", []string{"favorites items 0 500 want_url:1"}},
+		{"Missing favorite", "lms/player/cmd", "favorite", []string{}},
+		{"Sync", "lms/player/cmd", "sync leader", []string{"leader sync player"}},
+		{"Missing sync leader", "lms/player/cmd", "sync", []string{}},
+		{"Unsync", "lms/player/cmd", "unsync", []string{"player sync -"}},
+		{"Add alarm", "lms/player/cmd", "alarm add time:07:30 Days:1,2 playlist:http://example.com/Radio.mp3",
+			[]string{"player alarm add dow:1%2C2 time:27000 url:http:%2F%2Fexample.com%2FRadio.mp3"}},
+		{"Update alarm", "lms/player/cmd", "alarm update 8d1a2b volume:30", []string{"player alarm update id:8d1a2b volume:30"}},
+		{"Enable alarm", "lms/player/cmd", "ALARM Enable 8d1a2b", []string{"player alarm update id:8d1a2b enabled:1"}},
+		{"Disable alarm", "lms/player/cmd", "alarm disable 8d1a2b", []string{"player alarm update id:8d1a2b enabled:0"}},
+		{"Delete alarm", "lms/player/cmd", "alarm delete 8d1a2b", []string{"player alarm delete id:8d1a2b"}},
+		{"Missing alarm id", "lms/player/cmd", "alarm delete", []string{}},
+		{"Invalid alarm setting", "lms/player/cmd", "alarm add 07:30", []string{}},
+		{"Unknown alarm action", "lms/player/cmd", "alarm ring 8d1a2b", []string{}},
+		{"Sleep", "lms/player/cmd", "sleep 1800", []string{"player sleep 1800"}},
+		{"Cancel sleep", "lms/player/cmd", "sleep Cancel", []string{"player sleep 0"}},
+		{"Invalid sleep", "lms/player/cmd", "sleep 30m", []string{}},
+		{"Unexpected argument", "lms/player/cmd", "play 1", []string{}},
+		{"Empty", "lms/player/cmd", "", []string{}},
+		{"Unknown", "lms/player/cmd", "dance", []string{}},
+		{"Bad topic", "lms/player/other", "play", []string{}},
+	}
+
+	lms := lmsMock{}
+	if err := lms.listen(); err != nil {
+		t.Fatalf("unable to start lms mock: %v", err)
+	}
+	defer lms.Close()
+
+	app := application{topic: "lms", server: squeeze.New(lms.Addr())}
+	for _, c := range cases {
+		lms.Reset()
+		app.onCommand(nil, &messageMock{topic: c.topic, payload: []byte(c.payload)})
+
+		commands := lms.Commands()
+		if len(commands) != len(c.expectedCommands) {
+			t.Errorf("[%v] bad commands: %#v, wants %#v", c.name, commands, c.expectedCommands)
+			continue
+		}
+		for i := range commands {
+			if commands[i] != c.expectedCommands[i] {
+				t.Errorf("[%v] bad command: %#v, wants %#v", c.name, commands[i], c.expectedCommands[i])
+			}
+		}
+	}
+}
+
+type messageMock struct {
+	topic   string
+	payload []byte
+}
+
+func (m *messageMock) Duplicate() bool   { return false }
+func (m *messageMock) Qos() byte         { return 0 }
+func (m *messageMock) Retained() bool    { return false }
+func (m *messageMock) Topic() string     { return m.topic }
+func (m *messageMock) MessageID() uint16 { return 0 }
+func (m *messageMock) Payload() []byte   { return m.payload }
+func (m *messageMock) Ack()              {}
+
+// lmsMock is a minimal lms cli server that records commands and echoes them as response
+type lmsMock struct {
+	mu       sync.Mutex
+	commands []string
+	ln       net.Listener
+}
+
+func (l *lmsMock) listen() error {
+	ln, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		return err
+	}
+	l.ln = ln
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go l.handleConnection(conn)
+		}
+	}()
+	return nil
+}
+
+func (l *lmsMock) handleConnection(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		rawCmd, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(rawCmd, "\r\n")
+		l.mu.Lock()
+		l.commands = append(l.commands, cmd)
+		l.mu.Unlock()
+		if _, err := conn.Write([]byte(cmd + "\r\n")); err != nil {
+			return
+		}
+	}
+}
+
+func (l *lmsMock) Addr() string {
+	return l.ln.Addr().String()
+}
+
+func (l *lmsMock) Commands() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.commands...)
+}
+
+func (l *lmsMock) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.commands = nil
+}
+
+func (l *lmsMock) Close() error {
+	return l.ln.Close()
+}
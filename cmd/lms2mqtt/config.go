@@ -0,0 +1,385 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// config is the content of the configuration file, as yaml. Its values are the defaults of the command line flags:
+// flags take precedence over environment variables, which take precedence over the file.
+type config struct {
+	Lms              lmsConfig                           `yaml:"lms"`
+	Mqtt             mqttConfig                          `yaml:"mqtt"`
+	HomeAssistant    haSettings                          `yaml:"home_assistant"`
+	Artwork          string                              `yaml:"artwork"`
+	PositionInterval string                              `yaml:"position_interval"`
+	Debug            *bool                               `yaml:"debug"`
+	Parsers          []squeeze.RegexParserConfig         `yaml:"parsers"`
+	ParserRules      []squeeze.ParserRule                `yaml:"parser_rules"`
+	Players          map[squeeze.PlayerId]playerSettings `yaml:"players"`
+	// Servers replace the lms section and the mqtt topic to bridge many servers on the same mqtt connection
+	Servers []serverConfig `yaml:"servers"`
+}
+
+type lmsConfig struct {
+	Address      string `yaml:"address"`
+	Url          string `yaml:"url"`
+	WebUrl       string `yaml:"web_url"`
+	Username     string `yaml:"username"`
+	secretConfig `yaml:",inline"`
+}
+
+type mqttConfig struct {
+	Broker       string `yaml:"broker"`
+	ClientId     string `yaml:"client_id"`
+	Topic        string `yaml:"topic"`
+	Qos          *int   `yaml:"qos"`
+	Username     string `yaml:"username"`
+	secretConfig `yaml:",inline"`
+}
+
+// serverConfig is a bridged server, its players are published under its own topic prefix
+type serverConfig struct {
+	Name      string `yaml:"name"`
+	Topic     string `yaml:"topic"`
+	lmsConfig `yaml:",inline"`
+}
+
+// secretConfig is a password given as is, read from a file or from an environment variable
+type secretConfig struct {
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	PasswordEnv  string `yaml:"password_env"`
+}
+
+type haSettings struct {
+	Discovery *bool  `yaml:"discovery"`
+	Prefix    string `yaml:"prefix"`
+}
+
+// playerSettings override the bridge behavior for a single player
+type playerSettings struct {
+	// Parser is the metadata parser of the player tracks, it takes precedence over parser rules
+	Parser string `yaml:"parser"`
+	// HaDiscovery disable the home assistant discovery of the player when false
+	HaDiscovery *bool `yaml:"ha_discovery"`
+}
+
+// flagEnvs are the environment variables used as default values of flags
+var flagEnvs = map[string]string{
+	"lms-username":      "LMS_USERNAME",
+	"lms-password":      "LMS_PASSWORD",
+	"lms-password-file": "LMS_PASSWORD_FILE",
+	"mqtt-broker":       "MQTT_BROKER",
+	"mqtt-username":     "MQTT_USERNAME",
+	"mqtt-password":     "MQTT_PASSWORD",
+	"mqtt-client-id":    "MQTT_CLIENT_ID",
+}
+
+// loadConfig read and validate the yaml configuration file, unknown fields are rejected
+func loadConfig(path string) (*config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file %v: %v", path, err)
+	}
+
+	var cfg config
+	if err := yaml.UnmarshalStrict(content, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to decode config file %v: %v", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %v: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *config) validate() error {
+	if c.Lms.Address != "" && c.Lms.Url != "" {
+		return fmt.Errorf("lms: address and url are exclusive")
+	}
+	if err := c.Lms.validate(); err != nil {
+		return fmt.Errorf("lms: %v", err)
+	}
+	if err := c.Mqtt.validate(); err != nil {
+		return fmt.Errorf("mqtt: %v", err)
+	}
+	if c.Mqtt.Qos != nil && (*c.Mqtt.Qos < 0 || *c.Mqtt.Qos > 2) {
+		return fmt.Errorf("mqtt: invalid qos %v, must be 0, 1 or 2", *c.Mqtt.Qos)
+	}
+	if c.Artwork != "" {
+		if err := checkArtworkMode(c.Artwork); err != nil {
+			return fmt.Errorf("artwork: %v", err)
+		}
+	}
+	if c.PositionInterval != "" {
+		if _, err := time.ParseDuration(c.PositionInterval); err != nil {
+			return fmt.Errorf("position_interval: %v", err)
+		}
+	}
+	if err := c.validateServers(); err != nil {
+		return fmt.Errorf("servers: %v", err)
+	}
+	for id, p := range c.Players {
+		if p.Parser == "" && p.HaDiscovery == nil {
+			return fmt.Errorf("players: no setting for player %v", id)
+		}
+	}
+	return nil
+}
+
+var serverNamePattern = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+func (c *config) validateServers() error {
+	if len(c.Servers) == 0 {
+		return nil
+	}
+	if c.Lms != (lmsConfig{}) || c.Mqtt.Topic != "" {
+		return fmt.Errorf("lms section and mqtt topic can't be used with a servers list")
+	}
+	names := make(map[string]bool)
+	topics := make(map[string]bool)
+	for i, srv := range c.Servers {
+		if !serverNamePattern.MatchString(srv.Name) {
+			return fmt.Errorf("server %d: invalid name \"%v\", must be made of letters, digits, '_' or '-'", i, srv.Name)
+		}
+		if names[srv.Name] {
+			return fmt.Errorf("server %v: duplicated name", srv.Name)
+		}
+		names[srv.Name] = true
+		if srv.Topic == "" || topics[srv.Topic] {
+			return fmt.Errorf("server %v: topic must be defined and unique", srv.Name)
+		}
+		topics[srv.Topic] = true
+		if (srv.Address == "") == (srv.Url == "") {
+			return fmt.Errorf("server %v: either address or url must be defined", srv.Name)
+		}
+		if err := srv.validate(); err != nil {
+			return fmt.Errorf("server %v: %v", srv.Name, err)
+		}
+	}
+	return nil
+}
+
+// serverFlags are the flags of the single bridged server, replaced by a servers list
+var serverFlags = []string{"address", "lms-url", "lms-web-url", "lms-username", "lms-password", "lms-password-file",
+	"mqtt-topic"}
+
+// checkServerFlags reject the flags of the single server when a servers list is configured, they would be ignored
+func (c *config) checkServerFlags(fs *flag.FlagSet) error {
+	if len(c.Servers) == 0 {
+		return nil
+	}
+	given := givenFlags(fs)
+	for _, name := range serverFlags {
+		if !given[name] {
+			continue
+		}
+		if env, ok := flagEnvs[name]; ok {
+			return fmt.Errorf("flag -%v and env %v can't be used with a servers list, set them in the servers section", name, env)
+		}
+		return fmt.Errorf("flag -%v can't be used with a servers list, set it in the servers section", name)
+	}
+	return nil
+}
+
+// checkAddressFlags reject a cli address given with a web server url, as the config file does
+func checkAddressFlags(fs *flag.FlagSet) error {
+	given := givenFlags(fs)
+	if given["address"] && given["lms-url"] {
+		return fmt.Errorf("address and lms-url are exclusive")
+	}
+	return nil
+}
+
+// lmsServers return the servers of the servers list with their password
+func (c *config) lmsServers() ([]lmsServer, error) {
+	servers := make([]lmsServer, 0, len(c.Servers))
+	for _, srv := range c.Servers {
+		password, err := srv.value()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read password of server %v: %v", srv.Name, err)
+		}
+		servers = append(servers, lmsServer{
+			name:     srv.Name,
+			topic:    srv.Topic,
+			address:  srv.Address,
+			url:      srv.Url,
+			webUrl:   srv.WebUrl,
+			username: srv.Username,
+			password: password,
+		})
+	}
+	return servers, nil
+}
+
+func (s *secretConfig) validate() error {
+	count := 0
+	for _, v := range []string{s.Password, s.PasswordFile, s.PasswordEnv} {
+		if v != "" {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Errorf("password, password_file and password_env are exclusive")
+	}
+	return nil
+}
+
+// value return the password, empty when it isn't configured
+func (s *secretConfig) value() (string, error) {
+	switch {
+	case s.PasswordFile != "":
+		return readSecret(s.PasswordFile)
+	case s.PasswordEnv != "":
+		v, ok := os.LookupEnv(s.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %v isn't set", s.PasswordEnv)
+		}
+		return v, nil
+	default:
+		return s.Password, nil
+	}
+}
+
+// secretFlags are the flags and environment variables which take precedence over a configured password
+var secretFlags = map[string][]string{
+	"lms-password":  {"lms-password", "lms-password-file"},
+	"mqtt-password": {"mqtt-password"},
+}
+
+// flagValues return the configured values by flag name, settings not defined in the file are omitted.
+// Passwords are returned apart by secrets, to be read only when no flag overrides them.
+func (c *config) flagValues() map[string]string {
+	values := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	set("address", c.Lms.Address)
+	set("lms-url", c.Lms.Url)
+	set("lms-web-url", c.Lms.WebUrl)
+	set("lms-username", c.Lms.Username)
+
+	set("mqtt-broker", c.Mqtt.Broker)
+	set("mqtt-client-id", c.Mqtt.ClientId)
+	set("mqtt-topic", c.Mqtt.Topic)
+	set("mqtt-username", c.Mqtt.Username)
+	if c.Mqtt.Qos != nil {
+		set("mqtt-qos", strconv.Itoa(*c.Mqtt.Qos))
+	}
+
+	if c.HomeAssistant.Discovery != nil {
+		set("ha-discovery", strconv.FormatBool(*c.HomeAssistant.Discovery))
+	}
+	set("ha-discovery-prefix", c.HomeAssistant.Prefix)
+	set("artwork", c.Artwork)
+	set("position-interval", c.PositionInterval)
+	if c.Debug != nil {
+		set("debug", strconv.FormatBool(*c.Debug))
+	}
+	return values
+}
+
+// secrets return the configured passwords by flag name
+func (c *config) secrets() map[string]secretConfig {
+	return map[string]secretConfig{
+		"lms-password":  c.Lms.secretConfig,
+		"mqtt-password": c.Mqtt.secretConfig,
+	}
+}
+
+// givenFlags return the flags given on the command line or by their environment variable
+func givenFlags(fs *flag.FlagSet) map[string]bool {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for name, env := range flagEnvs {
+		if os.Getenv(env) != "" {
+			given[name] = true
+		}
+	}
+	return given
+}
+
+// applyConfig set flags to the configured values, unless they are given on the command line
+// or by their environment variable. Password files and variables are read only for the flags they set.
+func applyConfig(fs *flag.FlagSet, values map[string]string, secrets map[string]secretConfig) error {
+	given := givenFlags(fs)
+
+	names := make([]string, 0, len(values)+len(secrets))
+	for name := range values {
+		names = append(names, name)
+	}
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if given[name] {
+			continue
+		}
+		value, ok := values[name]
+		if secret, isSecret := secrets[name]; isSecret {
+			if overridden(given, secretFlags[name]) {
+				continue
+			}
+			password, err := secret.value()
+			if err != nil {
+				return fmt.Errorf("unable to read %v: %v", name, err)
+			}
+			value, ok = password, password != ""
+		}
+		if !ok {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %v value \"%v\": %v", name, value, err)
+		}
+	}
+	return nil
+}
+
+// overridden tell if one of the flags is given
+func overridden(given map[string]bool, names []string) bool {
+	for _, name := range names {
+		if given[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// playerParserRules select the parser of players with a parser override, sorted by player id
+func playerParserRules(players map[squeeze.PlayerId]playerSettings) []squeeze.ParserRule {
+	ids := make([]string, 0, len(players))
+	for id, p := range players {
+		if p.Parser != "" {
+			ids = append(ids, string(id))
+		}
+	}
+	sort.Strings(ids)
+
+	rules := make([]squeeze.ParserRule, 0, len(ids))
+	for _, id := range ids {
+		rules = append(rules, squeeze.ParserRule{
+			Field:  squeeze.RulePlayer,
+			Regex:  "^" + regexp.QuoteMeta(id) + "$",
+			Parser: players[squeeze.PlayerId(id)].Parser,
+		})
+	}
+	return rules
+}
+
+// haDiscoveryEnabled tell if the home assistant discovery of the player isn't disabled by its settings
+func (o *options) haDiscoveryEnabled(id squeeze.PlayerId) bool {
+	p, ok := o.players[id]
+	return !ok || p.HaDiscovery == nil || *p.HaDiscovery
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"flag"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "lms2mqtt")
+	if err != nil {
+		t.Fatalf("unable to create file: %v", err)
+	}
+	_, _ = f.WriteString(content)
+	_ = f.Close()
+	return f.Name()
+}
+
+func Test_loadConfig(t *testing.T) {
+
+	cases := []struct {
+		name           string
+		content        string
+		expectedValues map[string]string
+		expectedErr    string
+	}{
+		{"Full",
+			"# Kitchen and living room\n" +
+				"lms:\n" +
+				"  url: http://lms:9000\n" +
+				"  username: admin\n" +
+				"  password_file: /run/secrets/lms\n" +
+				"mqtt:\n" +
+				"  broker: tcp://mqtt:1883\n" +
+				"  topic: lms\n" +
+				"  qos: 1\n" +
+				"  password_env: LMS2MQTT_TEST_PASSWORD\n" +
+				"home_assistant: {discovery: true, prefix: ha}\n" +
+				"artwork: image\n" +
+				"position_interval: 10s\n" +
+				"debug: false\n" +
+				"players:\n" +
+				"  00:04:20:12:34:56: &radio {parser: radiofrance, ha_discovery: false}\n" +
+				"  00:04:20:12:34:57: *radio\n",
+			map[string]string{
+				"lms-url": "http://lms:9000", "lms-username": "admin",
+				"mqtt-broker": "tcp://mqtt:1883", "mqtt-topic": "lms", "mqtt-qos": "1",
+				"ha-discovery": "true", "ha-discovery-prefix": "ha", "artwork": "image",
+				"position-interval": "10s", "debug": "false",
+			}, ""},
+		{"Json", `{"lms": {"address": "lms:9090"}, "mqtt": {"topic": "lms"}}`,
+			map[string]string{"address": "lms:9090", "mqtt-topic": "lms"}, ""},
+		{"Empty", `{}`, map[string]string{}, ""},
+		{"Unknown field", "lms:\n  host: lms\n", nil, "field host not found"},
+		{"Invalid yaml", "lms: [", nil, "unable to decode"},
+		{"Address and url", `{"lms": {"address": "lms:9090", "url": "http://lms:9000"}}`, nil, "address and url are exclusive"},
+		{"Many passwords", `{"mqtt": {"password": "x", "password_file": "/run/secrets/mqtt"}}`, nil, "are exclusive"},
+		{"Invalid qos", `{"mqtt": {"qos": 3}}`, nil, "invalid qos 3"},
+		{"Invalid artwork", `{"artwork": "thumbnail"}`, nil, "unknown artwork mode"},
+		{"Invalid interval", `{"position_interval": "often"}`, nil, "position_interval"},
+		{"Empty player", `{"players": {"00:04:20:12:34:56": {}}}`, nil, "no setting for player"},
+		{"Servers", `{"servers": [{"name": "home", "topic": "lms/home", "address": "home:9090"},
+				{"name": "garage", "topic": "lms/garage", "url": "http://garage:9000"}]}`, map[string]string{}, ""},
+		{"Server invalid name", `{"servers": [{"name": "my home", "topic": "lms", "address": "home:9090"}]}`, nil, "invalid name"},
+		{"Server duplicated name", `{"servers": [{"name": "home", "topic": "lms/1", "address": "home:9090"},
+				{"name": "home", "topic": "lms/2", "address": "home:9090"}]}`, nil, "duplicated name"},
+		{"Server duplicated topic", `{"servers": [{"name": "home", "topic": "lms", "address": "home:9090"},
+				{"name": "garage", "topic": "lms", "address": "garage:9090"}]}`, nil, "topic must be defined and unique"},
+		{"Server without address", `{"servers": [{"name": "home", "topic": "lms"}]}`, nil, "either address or url"},
+		{"Servers and lms", `{"lms": {"address": "home:9090"}, "servers": [{"name": "home", "topic": "lms", "address": "home:9090"}]}`,
+			nil, "can't be used with a servers list"},
+	}
+
+	for _, c := range cases {
+		path := writeTempFile(t, c.content)
+		cfg, err := loadConfig(path)
+		var values map[string]string
+		if err == nil {
+			values = cfg.flagValues()
+		}
+		_ = os.Remove(path)
+
+		if c.expectedErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+				t.Errorf("[%v] bad error: %v, wants %#v", c.name, err, c.expectedErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, c.expectedValues) {
+			t.Errorf("[%v] bad values: %#v, wants %#v", c.name, values, c.expectedValues)
+		}
+	}
+
+	if _, err := loadConfig("/nonexistent/config.yaml"); err == nil {
+		t.Errorf("missing file should fail")
+	}
+}
+
+func Test_loadConfigPlayers(t *testing.T) {
+	path := writeTempFile(t, "players:\n"+
+		"  00:04:20:12:34:56: &radio {parser: radiofrance}\n"+
+		"  b8:27:eb:00:00:01: *radio\n"+
+		"parser_rules:\n"+
+		"  - {field: current_title, prefix: fip, parser: radiofrance}\n")
+	defer os.Remove(path)
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("unable to load config: %v", err)
+	}
+	expected := map[squeeze.PlayerId]playerSettings{
+		"00:04:20:12:34:56": {Parser: "radiofrance"},
+		"b8:27:eb:00:00:01": {Parser: "radiofrance"},
+	}
+	if !reflect.DeepEqual(cfg.Players, expected) {
+		t.Errorf("bad players: %#v, wants %#v", cfg.Players, expected)
+	}
+	rules := []squeeze.ParserRule{{Field: "current_title", Prefix: "fip", Parser: "radiofrance"}}
+	if !reflect.DeepEqual(cfg.ParserRules, rules) {
+		t.Errorf("bad parser rules: %#v, wants %#v", cfg.ParserRules, rules)
+	}
+}
+
+func Test_lmsServers(t *testing.T) {
+	_ = os.Setenv("LMS2MQTT_TEST_PASSWORD", "from-env")
+	defer os.Unsetenv("LMS2MQTT_TEST_PASSWORD")
+
+	cfg := config{Servers: []serverConfig{
+		{Name: "home", Topic: "lms/home", lmsConfig: lmsConfig{Address: "home:9090", Username: "admin",
+			secretConfig: secretConfig{PasswordEnv: "LMS2MQTT_TEST_PASSWORD"}}},
+		{Name: "garage", Topic: "lms/garage", lmsConfig: lmsConfig{Url: "http://garage:9000", WebUrl: "http://garage.local:9000"}},
+	}}
+	servers, err := cfg.lmsServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []lmsServer{
+		{name: "home", topic: "lms/home", address: "home:9090", username: "admin", password: "from-env"},
+		{name: "garage", topic: "lms/garage", url: "http://garage:9000", webUrl: "http://garage.local:9000"},
+	}
+	if !reflect.DeepEqual(servers, expected) {
+		t.Errorf("bad servers: %#v, wants %#v", servers, expected)
+	}
+
+	cfg.Servers[1].PasswordEnv = "LMS2MQTT_TEST_UNDEFINED"
+	if _, err := cfg.lmsServers(); err == nil || !strings.Contains(err.Error(), "server garage") {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+func Test_checkServerFlags(t *testing.T) {
+	_ = os.Unsetenv("LMS_USERNAME")
+	servers := &config{Servers: []serverConfig{{Name: "home", Topic: "lms/home", lmsConfig: lmsConfig{Address: "home:9090"}}}}
+
+	cases := []struct {
+		name        string
+		cfg         *config
+		args        []string
+		env         map[string]string
+		expectedErr string
+	}{
+		{"Single server", &config{}, []string{"-address=lms:9090", "-mqtt-topic=lms"}, nil, ""},
+		{"Servers", servers, []string{"-mqtt-broker=tcp://mqtt:1883", "-debug"}, nil, ""},
+		{"Servers and address", servers, []string{"-address=lms:9090"}, nil, "flag -address can't be used"},
+		{"Servers and topic", servers, []string{"-mqtt-topic=lms"}, nil, "flag -mqtt-topic can't be used"},
+		{"Servers and env", servers, nil, map[string]string{"LMS_USERNAME": "admin"}, "env LMS_USERNAME can't be used"},
+	}
+
+	for _, c := range cases {
+		for k, v := range c.env {
+			_ = os.Setenv(k, v)
+		}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("address", "127.0.0.1:9090", "")
+		fs.String("mqtt-topic", "", "")
+		fs.String("mqtt-broker", "", "")
+		fs.Bool("debug", false, "")
+		if err := fs.Parse(c.args); err != nil {
+			t.Fatalf("[%v] unable to parse flags: %v", c.name, err)
+		}
+
+		err := c.cfg.checkServerFlags(fs)
+		for k := range c.env {
+			_ = os.Unsetenv(k)
+		}
+
+		if c.expectedErr == "" {
+			if err != nil {
+				t.Errorf("[%v] unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+			t.Errorf("[%v] bad error: %v, wants %#v", c.name, err, c.expectedErr)
+		}
+	}
+}
+
+func Test_checkAddressFlags(t *testing.T) {
+	cases := []struct {
+		name        string
+		args        []string
+		expectedErr bool
+	}{
+		{"Default", nil, false},
+		{"Address", []string{"-address=lms:9090"}, false},
+		{"Url", []string{"-lms-url=http://lms:9000"}, false},
+		{"Address and url", []string{"-address=lms:9090", "-lms-url=http://lms:9000"}, true},
+	}
+
+	for _, c := range cases {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("address", "127.0.0.1:9090", "")
+		fs.String("lms-url", "", "")
+		if err := fs.Parse(c.args); err != nil {
+			t.Fatalf("[%v] unable to parse flags: %v", c.name, err)
+		}
+		if err := checkAddressFlags(fs); (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+	}
+}
+
+func Test_applyConfig(t *testing.T) {
+	_ = os.Setenv("LMS_USERNAME", "env-user")
+	defer os.Unsetenv("LMS_USERNAME")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	address := fs.String("address", "127.0.0.1:9090", "")
+	topic := fs.String("mqtt-topic", "", "")
+	username := fs.String("lms-username", os.Getenv("LMS_USERNAME"), "")
+	interval := fs.Duration("position-interval", 0, "")
+	debug := fs.Bool("debug", false, "")
+	if err := fs.Parse([]string{"-mqtt-topic=flag-topic"}); err != nil {
+		t.Fatalf("unable to parse flags: %v", err)
+	}
+
+	err := applyConfig(fs, map[string]string{
+		"address":           "lms:9090",
+		"mqtt-topic":        "file-topic",
+		"lms-username":      "file-user",
+		"position-interval": "5s",
+		"debug":             "true",
+	}, nil)
+	if err != nil {
+		t.Fatalf("unable to apply config: %v", err)
+	}
+	if *address != "lms:9090" {
+		t.Errorf("file value should replace default: %v", *address)
+	}
+	if *topic != "flag-topic" {
+		t.Errorf("flag should take precedence over file: %v", *topic)
+	}
+	if *username != "env-user" {
+		t.Errorf("env should take precedence over file: %v", *username)
+	}
+	if *interval != 5*time.Second || !*debug {
+		t.Errorf("bad typed values: %v, %v", *interval, *debug)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("debug", false, "")
+	if err := applyConfig(fs, map[string]string{"debug": "maybe"}, nil); err == nil {
+		t.Errorf("invalid value should fail")
+	}
+}
+
+func Test_applyConfigSecrets(t *testing.T) {
+	secret := writeTempFile(t, "secret\n")
+	defer os.Remove(secret)
+	_ = os.Setenv("LMS2MQTT_TEST_PASSWORD", "from-env")
+	defer os.Unsetenv("LMS2MQTT_TEST_PASSWORD")
+	_ = os.Unsetenv("MQTT_PASSWORD")
+
+	missingFile := secretConfig{PasswordFile: "/nonexistent/secret"}
+	missingEnv := secretConfig{PasswordEnv: "LMS2MQTT_TEST_UNDEFINED"}
+	cases := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		secrets     map[string]secretConfig
+		expectedLms string
+		expectedErr string
+	}{
+		{"File", nil, nil, map[string]secretConfig{"lms-password": {PasswordFile: secret}}, "secret", ""},
+		{"Env", nil, nil, map[string]secretConfig{"lms-password": {PasswordEnv: "LMS2MQTT_TEST_PASSWORD"}}, "from-env", ""},
+		{"Value", nil, nil, map[string]secretConfig{"lms-password": {Password: "value"}}, "value", ""},
+		{"Unset", nil, nil, map[string]secretConfig{"lms-password": {}}, "", ""},
+		{"Missing file", nil, nil, map[string]secretConfig{"lms-password": missingFile}, "", "unable to read lms-password"},
+		{"Missing env", nil, nil, map[string]secretConfig{"mqtt-password": missingEnv}, "", "isn't set"},
+		{"Missing file with flag", []string{"-lms-password=flag"}, nil,
+			map[string]secretConfig{"lms-password": missingFile}, "flag", ""},
+		{"Missing file with password file flag", []string{"-lms-password-file=/run/secrets/lms"}, nil,
+			map[string]secretConfig{"lms-password": missingFile}, "", ""},
+		{"Missing env with env", nil, map[string]string{"MQTT_PASSWORD": "env"},
+			map[string]secretConfig{"mqtt-password": missingEnv}, "", ""},
+	}
+
+	for _, c := range cases {
+		for k, v := range c.env {
+			_ = os.Setenv(k, v)
+		}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		lmsPassword := fs.String("lms-password", "", "")
+		fs.String("lms-password-file", "", "")
+		fs.String("mqtt-password", "", "")
+		if err := fs.Parse(c.args); err != nil {
+			t.Fatalf("[%v] unable to parse flags: %v", c.name, err)
+		}
+
+		err := applyConfig(fs, nil, c.secrets)
+		for k := range c.env {
+			_ = os.Unsetenv(k)
+		}
+
+		if c.expectedErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+				t.Errorf("[%v] bad error: %v, wants %#v", c.name, err, c.expectedErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+			continue
+		}
+		if *lmsPassword != c.expectedLms {
+			t.Errorf("[%v] bad lms password: %#v, wants %#v", c.name, *lmsPassword, c.expectedLms)
+		}
+	}
+}
+
+func Test_playerParserRules(t *testing.T) {
+	disabled := false
+	rules := playerParserRules(map[squeeze.PlayerId]playerSettings{
+		"b8:27:eb:00:00:01": {Parser: "default"},
+		"00:04:20:12:34:56": {Parser: "radiofrance"},
+		"00:04:20:00:00:02": {HaDiscovery: &disabled},
+	})
+	expected := []squeeze.ParserRule{
+		{Field: squeeze.RulePlayer, Regex: `^00:04:20:12:34:56$`, Parser: "radiofrance"},
+		{Field: squeeze.RulePlayer, Regex: `^b8:27:eb:00:00:01$`, Parser: "default"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("bad rules: %#v, wants %#v", rules, expected)
+	}
+}
+
+func Test_publishHomeAssistantDisabledPlayer(t *testing.T) {
+	disabled := false
+	client := clientMock{}
+	app := application{
+		client: &client,
+		params: &mqttTooling.MqttCliParameters{},
+		topic:  "lms",
+		opts: options{haDiscovery: true, haDiscoveryPrefix: "homeassistant", artwork: artworkUrl,
+			players: map[squeeze.PlayerId]playerSettings{"b8:27:eb:00:00:01": {HaDiscovery: &disabled}}},
+	}
+	kitchen := squeeze.Player{Id: "00:04:20:12:34:56", Name: "Kitchen"}
+	living := squeeze.Player{Id: "b8:27:eb:00:00:01", Name: "Living room"}
+
+	app.publishHomeAssistant([]squeeze.Player{kitchen, living})
+
+	if _, ok := client.Published("homeassistant/number/lms2mqtt_00_04_20_12_34_56/volume/config"); !ok {
+		t.Errorf("enabled player should be announced")
+	}
+	if _, ok := client.Published("homeassistant/number/lms2mqtt_b8_27_eb_00_00_01/volume/config"); ok {
+		t.Errorf("disabled player shouldn't be announced")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"regexp"
+)
+
+const (
+	defaultHaDiscoveryPrefix = "homeassistant"
+)
+
+type haDevice struct {
+	Identifiers []string `json:"identifiers"`
+	Name        string   `json:"name"`
+	Model       string   `json:"model,omitempty"`
+	SwVersion   string   `json:"sw_version,omitempty"`
+}
+
+// haAvailability is an availability topic of an entity
+type haAvailability struct {
+	Topic               string `json:"topic"`
+	PayloadAvailable    string `json:"payload_available"`
+	PayloadNotAvailable string `json:"payload_not_available"`
+}
+
+type haConfig struct {
+	Name             string           `json:"name"`
+	UniqueId         string           `json:"unique_id"`
+	Device           haDevice         `json:"device"`
+	Availability     []haAvailability `json:"availability"`
+	AvailabilityMode string           `json:"availability_mode,omitempty"`
+	StateTopic       string           `json:"state_topic,omitempty"`
+	ValueTemplate    string           `json:"value_template,omitempty"`
+	CommandTopic     string           `json:"command_topic,omitempty"`
+	CommandTemplate  string           `json:"command_template,omitempty"`
+	PayloadPress     string           `json:"payload_press,omitempty"`
+	PayloadOn        string           `json:"payload_on,omitempty"`
+	PayloadOff       string           `json:"payload_off,omitempty"`
+	StateOn          string           `json:"state_on,omitempty"`
+	StateOff         string           `json:"state_off,omitempty"`
+	Min              *int             `json:"min,omitempty"`
+	Max              *int             `json:"max,omitempty"`
+	ImageTopic       string           `json:"image_topic,omitempty"`
+	ContentType      string           `json:"content_type,omitempty"`
+	UrlTopic         string           `json:"url_topic,omitempty"`
+	UrlTemplate      string           `json:"url_template,omitempty"`
+}
+
+type haEntity struct {
+	component string
+	objectId  string
+	config    haConfig
+}
+
+var haInvalidChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+// haNodeId identify a player, the server name is only set when many servers are bridged
+func haNodeId(server string, id squeeze.PlayerId) string {
+	if server != "" {
+		return "lms2mqtt_" + server + "_" + haInvalidChars.ReplaceAllString(string(id), "_")
+	}
+	return "lms2mqtt_" + haInvalidChars.ReplaceAllString(string(id), "_")
+}
+
+func haConfigTopic(discoveryPrefix, server string, id squeeze.PlayerId, e haEntity) string {
+	return fmt.Sprintf("%v/%v/%v/%v/config", discoveryPrefix, e.component, haNodeId(server, id), e.objectId)
+}
+
+// haAvailabilities return the availability of the server status topic and, when it differs, of the bridge topic
+// which is flagged offline by the last will
+func haAvailabilities(bridgeTopic, prefix string) []haAvailability {
+	topics := []string{bridgeStatusTopic(prefix)}
+	if bridgeTopic != "" && bridgeTopic != topics[0] {
+		topics = append([]string{bridgeTopic}, topics...)
+	}
+	availabilities := make([]haAvailability, 0, len(topics))
+	for _, topic := range topics {
+		availabilities = append(availabilities, haAvailability{
+			Topic:               topic,
+			PayloadAvailable:    bridgeOnline,
+			PayloadNotAvailable: bridgeOffline,
+		})
+	}
+	return availabilities
+}
+
+// haEntities describe the home assistant entities of a player, the artwork image depends on the artwork mode.
+// Entities are available when the bridge and the server status topics are both online.
+func haEntities(server, bridgeTopic, prefix string, p squeeze.Player, artwork string) []haEntity {
+	minVolume, maxVolume := 0, 100
+	device := haDevice{
+		Identifiers: []string{haNodeId(server, p.Id)},
+		Name:        p.Name,
+		Model:       p.ModelName,
+		SwVersion:   p.Firmware,
+	}
+	availability := haAvailabilities(bridgeTopic, prefix)
+	entity := func(component, objectId, name string, config haConfig) haEntity {
+		config.Name = name
+		config.UniqueId = fmt.Sprintf("%v_%v", haNodeId(server, p.Id), objectId)
+		config.Device = device
+		config.Availability = availability
+		if len(availability) > 1 {
+			config.AvailabilityMode = "all"
+		}
+		return haEntity{component: component, objectId: objectId, config: config}
+	}
+	cmd := playerTopic(prefix, p.Id, "cmd")
+
+	entities := []haEntity{
+		entity("sensor", "artist", "Artist", haConfig{StateTopic: trackTopic(prefix, p.Id), ValueTemplate: "{{ value_json.Artist }}"}),
+		entity("sensor", "title", "Title", haConfig{StateTopic: trackTopic(prefix, p.Id), ValueTemplate: "{{ value_json.Title }}"}),
+		entity("sensor", "album", "Album", haConfig{StateTopic: trackTopic(prefix, p.Id), ValueTemplate: "{{ value_json.Album }}"}),
+		entity("sensor", "state", "State", haConfig{StateTopic: stateTopic(prefix, p.Id)}),
+		entity("switch", "power", "Power", haConfig{
+			StateTopic:   powerTopic(prefix, p.Id),
+			CommandTopic: cmd,
+			PayloadOn:    "power on",
+			PayloadOff:   "power off",
+			StateOn:      "on",
+			StateOff:     "off",
+		}),
+		entity("number", "volume", "Volume", haConfig{
+			StateTopic:      mixerTopic(prefix, p.Id),
+			ValueTemplate:   "{{ value_json.Volume }}",
+			CommandTopic:    cmd,
+			CommandTemplate: "volume {{ value | int }}",
+			Min:             &minVolume,
+			Max:             &maxVolume,
+		}),
+		entity("button", "play", "Play", haConfig{CommandTopic: cmd, PayloadPress: "play"}),
+		entity("button", "pause", "Pause", haConfig{CommandTopic: cmd, PayloadPress: "pause"}),
+		entity("button", "next", "Next", haConfig{CommandTopic: cmd, PayloadPress: "next"}),
+		entity("button", "previous", "Previous", haConfig{CommandTopic: cmd, PayloadPress: "previous"}),
+	}
+	switch artwork {
+	case artworkUrl:
+		entities = append(entities, entity("image", "artwork", "Artwork", haConfig{
+			UrlTopic:    trackTopic(prefix, p.Id),
+			UrlTemplate: "{{ value_json.ArtworkUrl }}",
+		}))
+	case artworkImage:
+		entities = append(entities, entity("image", "artwork", "Artwork", haConfig{
+			ImageTopic:  artTopic(prefix, p.Id),
+			ContentType: "image/jpeg",
+		}))
+	}
+	return entities
+}
+
+// publishHomeAssistant publish discovery configs of the players and remove the ones of forgotten players
+func (a *application) publishHomeAssistant(players []squeeze.Player) {
+	announced := make(map[squeeze.PlayerId]squeeze.Player, len(players))
+	for _, p := range players {
+		// Players with disabled discovery are removed as forgotten players
+		if !a.opts.haDiscoveryEnabled(p.Id) {
+			continue
+		}
+		for _, e := range haEntities(a.name, a.bridgeTopic, a.topic, p, a.opts.artwork) {
+			a.publishJson(haConfigTopic(a.opts.haDiscoveryPrefix, a.name, p.Id, e), true, e.config)
+		}
+		announced[p.Id] = p
+	}
+
+	for id, p := range a.haAnnounced {
+		if _, ok := announced[id]; ok {
+			continue
+		}
+		for _, e := range haEntities(a.name, a.bridgeTopic, a.topic, p, a.opts.artwork) {
+			a.publish(haConfigTopic(a.opts.haDiscoveryPrefix, a.name, id, e), true, []byte{})
+		}
+	}
+	a.haAnnounced = announced
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"reflect"
+	"testing"
+)
+
+func Test_haNodeId(t *testing.T) {
+	cases := []struct {
+		name           string
+		server         string
+		id             squeeze.PlayerId
+		expectedNodeId string
+	}{
+		{"Mac address", "", "00:04:20:12:34:56", "lms2mqtt_00_04_20_12_34_56"},
+		{"Simple", "", "player-1", "lms2mqtt_player-1"},
+		{"Named server", "garage", "00:04:20:12:34:56", "lms2mqtt_garage_00_04_20_12_34_56"},
+	}
+	for _, c := range cases {
+		if nodeId := haNodeId(c.server, c.id); nodeId != c.expectedNodeId {
+			t.Errorf("[%v] bad node id: %#v, wants %#v", c.name, nodeId, c.expectedNodeId)
+		}
+	}
+}
+
+func Test_haAvailabilities(t *testing.T) {
+	cases := []struct {
+		name           string
+		bridgeTopic    string
+		prefix         string
+		expectedTopics []string
+	}{
+		{"Single server", "lms/bridge/status", "lms", []string{"lms/bridge/status"}},
+		{"No bridge topic", "", "lms", []string{"lms/bridge/status"}},
+		{"Many servers", "lms2mqtt/bridge/status", "lms/home", []string{"lms2mqtt/bridge/status", "lms/home/bridge/status"}},
+	}
+	for _, c := range cases {
+		var topics []string
+		for _, a := range haAvailabilities(c.bridgeTopic, c.prefix) {
+			if a.PayloadAvailable != bridgeOnline || a.PayloadNotAvailable != bridgeOffline {
+				t.Errorf("[%v] bad payloads: %#v", c.name, a)
+			}
+			topics = append(topics, a.Topic)
+		}
+		if !reflect.DeepEqual(topics, c.expectedTopics) {
+			t.Errorf("[%v] bad topics: %#v, wants %#v", c.name, topics, c.expectedTopics)
+		}
+	}
+
+	// Entities of many servers are available only when both topics are online
+	for _, e := range haEntities("home", "lms2mqtt/bridge/status", "lms/home", squeeze.Player{Id: "player-1"}, artworkUrl) {
+		if e.config.AvailabilityMode != "all" || len(e.config.Availability) != 2 {
+			t.Errorf("bad availability of %v: %#v, mode %#v", e.objectId, e.config.Availability, e.config.AvailabilityMode)
+		}
+	}
+}
+
+func Test_publishHomeAssistant(t *testing.T) {
+	client := clientMock{}
+	app := application{
+		client: &client,
+		params: &mqttTooling.MqttCliParameters{},
+		topic:  "lms",
+		opts:   options{haDiscovery: true, haDiscoveryPrefix: "homeassistant", artwork: artworkImage},
+	}
+	kitchen := squeeze.Player{Id: "00:04:20:12:34:56", Name: "Kitchen", ModelName: "Squeezebox Receiver", Firmware: "77"}
+	living := squeeze.Player{Id: "b8:27:eb:00:00:01", Name: "Living room"}
+
+	app.publishHomeAssistant([]squeeze.Player{kitchen, living})
+
+	p, ok := client.Published("homeassistant/number/lms2mqtt_00_04_20_12_34_56/volume/config")
+	if !ok {
+		t.Fatalf("no volume config published: %v", client.Publications())
+	}
+	if !p.retained {
+		t.Errorf("discovery config should be retained")
+	}
+	var config haConfig
+	if err := json.Unmarshal(p.payload, &config); err != nil {
+		t.Fatalf("unable to unmarshal config: %v", err)
+	}
+	if config.StateTopic != "lms/00:04:20:12:34:56/mixer" {
+		t.Errorf("bad state topic: %#v", config.StateTopic)
+	}
+	if config.CommandTopic != "lms/00:04:20:12:34:56/cmd" {
+		t.Errorf("bad command topic: %#v", config.CommandTopic)
+	}
+	availability := []haAvailability{{Topic: "lms/bridge/status", PayloadAvailable: "online", PayloadNotAvailable: "offline"}}
+	if !reflect.DeepEqual(config.Availability, availability) || config.AvailabilityMode != "" {
+		t.Errorf("bad availability: %#v, mode %#v", config.Availability, config.AvailabilityMode)
+	}
+	if config.UniqueId != "lms2mqtt_00_04_20_12_34_56_volume" {
+		t.Errorf("bad unique id: %#v", config.UniqueId)
+	}
+	if config.Device.Name != "Kitchen" || config.Device.Model != "Squeezebox Receiver" || config.Device.SwVersion != "77" {
+		t.Errorf("bad device: %#v", config.Device)
+	}
+	if config.Min == nil || *config.Min != 0 || config.Max == nil || *config.Max != 100 {
+		t.Errorf("bad volume range: %v, %v", config.Min, config.Max)
+	}
+
+	p, ok = client.Published("homeassistant/switch/lms2mqtt_00_04_20_12_34_56/power/config")
+	if !ok {
+		t.Fatalf("no power config published")
+	}
+	config = haConfig{}
+	if err := json.Unmarshal(p.payload, &config); err != nil {
+		t.Fatalf("unable to unmarshal config: %v", err)
+	}
+	if config.StateTopic != "lms/00:04:20:12:34:56/power" || config.StateOn != "on" || config.StateOff != "off" {
+		t.Errorf("bad power config: %#v", config)
+	}
+
+	p, ok = client.Published("homeassistant/image/lms2mqtt_00_04_20_12_34_56/artwork/config")
+	if !ok {
+		t.Fatalf("no artwork config published")
+	}
+	config = haConfig{}
+	if err := json.Unmarshal(p.payload, &config); err != nil {
+		t.Fatalf("unable to unmarshal config: %v", err)
+	}
+	if config.ImageTopic != "lms/00:04:20:12:34:56/art" || config.ContentType != "image/jpeg" {
+		t.Errorf("bad artwork config: %#v", config)
+	}
+
+	expectedCount := 2 * len(haEntities("", "", "lms", kitchen, artworkImage))
+	if count := len(client.Publications()); count != expectedCount {
+		t.Errorf("bad publications count: %v, wants %v", count, expectedCount)
+	}
+
+	// Forgotten players are removed from home assistant
+	client.Reset()
+	app.publishHomeAssistant([]squeeze.Player{living})
+	p, ok = client.Published("homeassistant/button/lms2mqtt_00_04_20_12_34_56/play/config")
+	if !ok || len(p.payload) != 0 || !p.retained {
+		t.Errorf("config of forgotten player should be removed: %#v", p)
+	}
+}
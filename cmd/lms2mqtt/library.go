@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLibraryCount = 50
+	// maxLibraryRequests is the number of library requests run at once by all servers
+	maxLibraryRequests = 4
+)
+
+// librarySlots limit the concurrent library requests, the mqtt client and its dispatch are shared by all servers
+var librarySlots = make(chan struct{}, maxLibraryRequests)
+
+// libraryRequest is a library query, the response is published on the reply topic with the same id
+type libraryRequest struct {
+	Id      string            `json:"id"`
+	Reply   string            `json:"reply"`
+	Command string            `json:"command"`
+	Start   int               `json:"start"`
+	Count   int               `json:"count"`
+	Search  string            `json:"search,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
+type libraryResponse struct {
+	Id      string              `json:"id"`
+	Command string              `json:"command"`
+	Start   int                 `json:"start"`
+	Total   int                 `json:"total"`
+	Items   []map[string]string `json:"items"`
+	Error   string              `json:"error,omitempty"`
+}
+
+func (a *application) onLibraryRequest(_ MQTT.Client, message MQTT.Message) {
+	var req libraryRequest
+	if err := json.Unmarshal(message.Payload(), &req); err != nil {
+		log.Warnf("unable to decode library request %s: %v", message.Payload(), err)
+		return
+	}
+	if req.Reply == "" {
+		log.Warnf("no reply topic in library request %v", req.Id)
+		return
+	}
+	if req.Count == 0 {
+		req.Count = defaultLibraryCount
+	}
+
+	// Message handlers are run one at a time, a slow library query mustn't delay player commands
+	a.pendingLibrary.Add(1)
+	go func() {
+		defer a.pendingLibrary.Done()
+		librarySlots <- struct{}{}
+		defer func() { <-librarySlots }()
+		a.runLibraryRequest(req)
+	}()
+}
+
+// runLibraryRequest query the library and publish the response on the reply topic
+func (a *application) runLibraryRequest(req libraryRequest) {
+	resp := libraryResponse{Id: req.Id, Command: req.Command, Start: req.Start, Items: []map[string]string{}}
+	result, err := a.server.Library(squeeze.LibraryQuery{
+		Command: req.Command,
+		Start:   req.Start,
+		Count:   req.Count,
+		Search:  req.Search,
+		Params:  req.Params,
+	})
+	if err != nil {
+		log.Errorf("unable to run library request %v: %v", req.Id, err)
+		resp.Error = err.Error()
+	} else {
+		resp.Total = result.Count
+		resp.Items = result.Items
+	}
+	a.publishJson(req.Reply, false, resp)
+}
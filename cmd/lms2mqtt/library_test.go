@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_onLibraryRequest(t *testing.T) {
+	cases := []struct {
+		name            string
+		payload         string
+		expectedCommand string
+		expectedError   bool
+	}{
+		{"Artists", `{"id": "1", "reply": "ui/reply", "command": "artists", "search": "little"}`, "artists 0 50 search:little", false},
+		{"Albums page", `{"id": "2", "reply": "ui/reply", "command": "albums", "start": 20, "count": 10, "params": {"artist_id": "12"}}`,
+			"albums 20 10 artist_id:12", false},
+		{"Search", `{"id": "3", "reply": "ui/reply", "command": "search", "search": "lucille"}`, "search 0 50 term:lucille", false},
+		{"Unknown command", `{"id": "4", "reply": "ui/reply", "command": "players"}`, "", true},
+		{"No reply topic", `{"id": "5", "command": "artists"}`, "", false},
+		{"Invalid json", `artists`, "", false},
+	}
+
+	lms := lmsMock{}
+	if err := lms.listen(); err != nil {
+		t.Fatalf("unable to start lms mock: %v", err)
+	}
+	defer lms.Close()
+
+	for _, c := range cases {
+		lms.Reset()
+		client := &clientMock{}
+		app := application{client: client, params: &mqttTooling.MqttCliParameters{}, topic: "lms", server: squeeze.New(lms.Addr())}
+		app.onLibraryRequest(nil, &messageMock{topic: libraryRequestTopic("lms"), payload: []byte(c.payload)})
+		app.pendingLibrary.Wait()
+
+		commands := lms.Commands()
+		if c.expectedCommand == "" {
+			if len(commands) != 0 {
+				t.Errorf("[%v] unexpected commands: %#v", c.name, commands)
+			}
+		} else if len(commands) != 1 || commands[0] != c.expectedCommand {
+			t.Errorf("[%v] bad commands: %#v, wants %v", c.name, commands, c.expectedCommand)
+		}
+
+		p, ok := client.Published("ui/reply")
+		if c.expectedCommand == "" && !c.expectedError {
+			if ok {
+				t.Errorf("[%v] unexpected reply: %s", c.name, p.payload)
+			}
+			continue
+		}
+		if !ok || p.retained {
+			t.Errorf("[%v] a non retained reply is expected: %#v", c.name, client.Publications())
+			continue
+		}
+		var resp libraryResponse
+		if err := json.Unmarshal(p.payload, &resp); err != nil {
+			t.Errorf("[%v] bad reply %s: %v", c.name, p.payload, err)
+		}
+		var req libraryRequest
+		_ = json.Unmarshal([]byte(c.payload), &req)
+		if resp.Id != req.Id || resp.Command != req.Command || (resp.Error != "") != c.expectedError {
+			t.Errorf("[%v] bad reply: %#v", c.name, resp)
+		}
+	}
+}
+
+func Test_onLibraryRequestAsync(t *testing.T) {
+	// The server accepts the connection but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	server := squeeze.New(listener.Addr().String())
+	defer server.Close()
+	app := application{client: &clientMock{}, params: &mqttTooling.MqttCliParameters{}, topic: "lms", server: server}
+
+	done := make(chan struct{})
+	go func() {
+		app.onLibraryRequest(nil, &messageMock{topic: libraryRequestTopic("lms"),
+			payload: []byte(`{"id": "1", "reply": "ui/reply", "command": "artists"}`)})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("library request shouldn't block the message handler")
+	}
+}
@@ -8,7 +8,11 @@ import (
 	"github.com/cyrilix/mqtt-tools/mqttTooling"
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	log "github.com/sirupsen/logrus"
+	"io/ioutil"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -21,43 +25,105 @@ type RunInterruptable interface {
 	Stop()
 }
 
+type options struct {
+	haDiscovery       bool
+	haDiscoveryPrefix string
+	positionInterval  time.Duration
+	parserRules       []squeeze.ParserRule
+	parsers           []squeeze.RegexParserConfig
+	artwork           string
+	players           map[squeeze.PlayerId]playerSettings
+}
+
+// lmsServer is a bridged server, its players are published under its own topic prefix
+type lmsServer struct {
+	name     string
+	topic    string
+	address  string
+	url      string
+	webUrl   string
+	username string
+	password string
+}
+
+// application bridge the players of a single server, its mqtt client is shared with the other servers
 type application struct {
 	client  MQTT.Client
 	params  *mqttTooling.MqttCliParameters
+	name    string
 	topic   string
 	address string
+	opts    options
+	server  *squeeze.Server
+	// bridgeTopic is the availability topic of the whole bridge, set offline by the mqtt last will
+	bridgeTopic string
+
+	haAnnounced map[squeeze.PlayerId]squeeze.Player
+	leaders     map[squeeze.PlayerId]squeeze.PlayerId
+
+	// pendingLibrary counts the library requests being answered
+	pendingLibrary sync.WaitGroup
+
+	muArtworks sync.Mutex
+	artworks   map[squeeze.PlayerId]*playerArtwork
+	// pendingArtworks counts the goroutines downloading artworks
+	pendingArtworks sync.WaitGroup
 }
 
-var newApplication = func(mcp *mqttTooling.MqttCliParameters, topic, serverAddress string) (RunInterruptable, error) {
-	app := &application{
-		params:  mcp,
-		topic:   topic,
-		address: serverAddress,
+var newApplication = func(mcp *mqttTooling.MqttCliParameters, servers []lmsServer, opts options) (RunInterruptable, error) {
+	if err := registerParsers(opts.parsers); err != nil {
+		return nil, fmt.Errorf("invalid parsers: %v", err)
+	}
+	b := &bridge{params: mcp, topic: bridgeStatusTopic(mcp.ClientId)}
+	if len(servers) == 1 {
+		b.topic = bridgeStatusTopic(servers[0].topic)
 	}
-	err := app.connect()
+	for _, srv := range servers {
+		app, err := newServerApplication(mcp, srv, opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server %v: %v", srv.topic, err)
+		}
+		app.bridgeTopic = b.topic
+		b.apps = append(b.apps, app)
+	}
+	err := b.connect()
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to mqtt bus: %v", err)
 	}
-	return app, nil
+	return b, nil
 }
 
-func (a *application) connect() error {
-	if a.client != nil && a.client.IsConnected() {
-		return fmt.Errorf("connection already exists")
+func newServerApplication(mcp *mqttTooling.MqttCliParameters, srv lmsServer, opts options) (*application, error) {
+	app := &application{
+		params:  mcp,
+		name:    srv.name,
+		topic:   srv.topic,
+		address: srv.address,
+		opts:    opts,
 	}
-	client, err := mqttTooling.Connect(a.params)
-	if err != nil {
-		return fmt.Errorf("unable to connect to mqtt bus: %v", err)
+	if srv.url != "" {
+		app.address = srv.url
+		app.server = squeeze.NewJsonRpc(srv.url)
+	} else {
+		app.server = squeeze.New(srv.address)
 	}
-	a.client = client
-	return nil
-}
-
-func (a *application) Stop() {
-	if a.client != nil && a.client.IsConnected() {
-		log.Info("Stop mqtt connection")
-		a.client.Disconnect(50)
+	app.server.SetCredentials(srv.username, srv.password)
+	if srv.webUrl != "" {
+		app.server.SetWebUrl(srv.webUrl)
+	}
+	rules := opts.parserRules
+	if overrides := playerParserRules(opts.players); len(overrides) > 0 {
+		if rules == nil {
+			rules = squeeze.DefaultParserRules
+		}
+		rules = append(overrides, rules...)
+	}
+	if rules != nil {
+		if err := app.server.SetParserRules(rules); err != nil {
+			return nil, fmt.Errorf("invalid parser rules: %v", err)
+		}
 	}
+	return app, nil
 }
 
 func (a *application) Subscribe(topic string, onMessage MQTT.MessageHandler) error {
@@ -68,12 +134,20 @@ func (a *application) Subscribe(topic string, onMessage MQTT.MessageHandler) err
 
 func (a *application) Run() error {
 
-	s := squeeze.New(a.address)
+	s := a.server
+	err := a.Subscribe(commandTopic(a.topic), a.onCommand)
+	if err != nil {
+		return fmt.Errorf("unable to subscribe to command topic: %v", err)
+	}
+	err = a.Subscribe(libraryRequestTopic(a.topic), a.onLibraryRequest)
+	if err != nil {
+		return fmt.Errorf("unable to subscribe to library topic: %v", err)
+	}
+	a.publish(bridgeStatusTopic(a.topic), true, []byte(bridgeOnline))
+
+	chanListen := make(chan error, 1)
 	go func() {
-		err := s.Listen()
-		if err != nil {
-			log.Panicf("unable to connect to %v instance: %v", a.address, err)
-		}
+		chanListen <- s.Listen()
 	}()
 	defer func() {
 		if err := s.Close(); err != nil {
@@ -81,51 +155,219 @@ func (a *application) Run() error {
 		}
 	}()
 
+	if a.opts.positionInterval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go a.watchPositions(a.opts.positionInterval, done)
+	}
+
 	chanTrack := s.NotifyTrackChange()
+	chanMixer := s.NotifyMixerChange()
+	chanPlayers := s.NotifyPlayersChange()
+	chanState := s.NotifyConnectionChange()
+	chanPlayback := s.NotifyPlaybackChange()
+	chanPlaylist := s.NotifyPlaylistChange()
+	chanFavorites := s.NotifyFavoritesChange()
+	chanSync := s.NotifySyncChange()
+	chanAlarms := s.NotifyAlarmsChange()
+	chanAlarmEvents := s.NotifyAlarmEvent()
+	chanSleep := s.NotifySleepChange()
+	chanPower := s.NotifyPowerChange()
+	// Events are published from the loop to keep their order on retained topics
 	for {
-		for t := range chanTrack {
-			go a.publishTrack(a.topic, t)
+		select {
+		case t, ok := <-chanTrack:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishTrack(t)
+		case m, ok := <-chanMixer:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishJson(mixerTopic(a.topic, m.Player), true, m)
+		case p, ok := <-chanPlayers:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishJson(playersTopic(a.topic), true, p)
+			if a.opts.haDiscovery {
+				a.publishHomeAssistant(p)
+			}
+		case p, ok := <-chanPlayback:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publish(stateTopic(a.topic, p.Player), true, []byte(p.State))
+		case p, ok := <-chanPlaylist:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishPlaylist(p)
+		case f, ok := <-chanFavorites:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishJson(favoritesTopic(a.topic), true, f)
+		case g, ok := <-chanSync:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishSyncGroups(g)
+		case al, ok := <-chanAlarms:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishJson(alarmsTopic(a.topic, al.Player), true, al.Alarms)
+		case e, ok := <-chanAlarmEvents:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishJson(alarmTopic(a.topic, e.Player), false, e)
+		case sl, ok := <-chanSleep:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publishJson(sleepTopic(a.topic, sl.Player), true, sl)
+		case p, ok := <-chanPower:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			a.publish(powerTopic(a.topic, p.Player), true, []byte(powerPayload(p.On)))
+		case state, ok := <-chanState:
+			if !ok {
+				return a.listenError(chanListen)
+			}
+			log.Infof("connection to %v %v", a.address, state)
+			a.publish(serverStatusTopic(a.topic), true, []byte(state))
 		}
 	}
 }
 
-func (a *application) publishTrack(topic string, t *squeeze.Track) {
-	content, err := json.Marshal(*t)
+func powerPayload(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// listenError wait the end of Listen once notification channels are closed
+func (a *application) listenError(chanListen <-chan error) error {
+	if err := <-chanListen; err != nil {
+		return fmt.Errorf("unable to listen %v instance: %v", a.address, err)
+	}
+	return nil
+}
+
+func (a *application) publishJson(topic string, retain bool, value interface{}) {
+	content, err := json.Marshal(value)
 	if err != nil {
-		log.Errorf("unable to marshall message %#v: %v", *t, err)
+		log.Errorf("unable to marshall message %#v: %v", value, err)
 		return
 	}
-	a.client.Publish(topic, byte(a.params.Qos), a.params.Retain, content)
+	a.publish(topic, retain, content)
+}
+
+func (a *application) publish(topic string, retain bool, content []byte) {
+	log.Debugf("publish to %v: %s", topic, content)
+	a.client.Publish(topic, byte(a.params.Qos), retain, content)
 }
 
 func main() {
-	var topic, address string
+	var passwordFile, parserRulesFile, parsersFile, configFile string
 	var debug bool
+	var opts options
+	var srv lmsServer
 
 	parameters := mqttTooling.MqttCliParameters{ClientId: defaultClientId}
-	flag.StringVar(&topic, "mqtt-topic", "", "The topic name to/from which to publish/subscribe")
-	flag.StringVar(&address, "address", "127.0.0.1:9090", "The squeezebox server address")
+	flag.StringVar(&configFile, "config", os.Getenv("LMS2MQTT_CONFIG"), "Yaml configuration file, flags and environment variables take precedence over its values, env LMS2MQTT_CONFIG")
+	flag.StringVar(&srv.topic, "mqtt-topic", "", "The topic prefix to/from which to publish/subscribe")
+	flag.StringVar(&srv.address, "address", "127.0.0.1:9090", "The squeezebox server address")
 	flag.BoolVar(&debug, "debug", false, "Display debug logs")
+	flag.BoolVar(&opts.haDiscovery, "ha-discovery", false, "Publish Home Assistant discovery configuration for each player")
+	flag.StringVar(&opts.haDiscoveryPrefix, "ha-discovery-prefix", defaultHaDiscoveryPrefix, "The Home Assistant discovery topic prefix")
+	flag.StringVar(&srv.url, "lms-url", "", "The squeezebox web server url, as http://127.0.0.1:9000, to use JSON-RPC instead of the cli port")
+	flag.StringVar(&srv.username, "lms-username", os.Getenv("LMS_USERNAME"), "The squeezebox server cli user, env LMS_USERNAME")
+	flag.StringVar(&srv.password, "lms-password", os.Getenv("LMS_PASSWORD"), "The squeezebox server cli password, env LMS_PASSWORD")
+	flag.StringVar(&passwordFile, "lms-password-file", os.Getenv("LMS_PASSWORD_FILE"), "File containing the squeezebox server cli password, env LMS_PASSWORD_FILE")
+	flag.StringVar(&parsersFile, "parsers", "", "Json file with the regex metadata parsers available to parser rules")
+	flag.StringVar(&parserRulesFile, "parser-rules", "", "Json file with the ordered rules to select the metadata parser of tracks")
+	flag.StringVar(&opts.artwork, "artwork", artworkUrl, "Artwork publication: 'url' in track payload, 'image' also publishes the image on the art topic, or 'none'")
+	flag.StringVar(&srv.webUrl, "lms-web-url", "", "The squeezebox web server url used for artworks, default to port 9000 of the cli address")
+	flag.DurationVar(&opts.positionInterval, "position-interval", 0, "Interval between position updates of playing players, 0 to disable")
 
 	mqttTooling.InitMqttFlagSet(&parameters)
 	flag.Parse()
-	if len(os.Args) <= 1 {
+	if len(os.Args) <= 1 && configFile == "" {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
+	var cfg *config
+	if configFile != "" {
+		var err error
+		cfg, err = loadConfig(configFile)
+		if err != nil {
+			log.Fatalf("unable to load configuration: %v", err)
+		}
+		if err := cfg.checkServerFlags(flag.CommandLine); err != nil {
+			log.Fatalf("invalid configuration: %v", err)
+		}
+		if err := applyConfig(flag.CommandLine, cfg.flagValues(), cfg.secrets()); err != nil {
+			log.Fatalf("invalid configuration: %v", err)
+		}
+		opts.players = cfg.Players
+	}
+
 	configureLogs(debug)
 
-	app, err := newApplication(&parameters, topic, address)
-	if err != nil {
-		log.Fatalf("unable to start application: %v", err)
+	if err := checkAddressFlags(flag.CommandLine); err != nil {
+		log.Fatalf("invalid lms options: %v", err)
+	}
+	if err := checkArtworkMode(opts.artwork); err != nil {
+		log.Fatalf("invalid artwork option: %v", err)
+	}
+
+	if passwordFile != "" {
+		password, err := readSecret(passwordFile)
+		if err != nil {
+			log.Fatalf("unable to read lms password: %v", err)
+		}
+		srv.password = password
+	}
+	if parsersFile != "" {
+		parsers, err := loadParsers(parsersFile)
+		if err != nil {
+			log.Fatalf("unable to load parsers: %v", err)
+		}
+		opts.parsers = parsers
+	} else if cfg != nil {
+		opts.parsers = cfg.Parsers
+	}
+	if parserRulesFile != "" {
+		rules, err := loadParserRules(parserRulesFile)
+		if err != nil {
+			log.Fatalf("unable to load parser rules: %v", err)
+		}
+		opts.parserRules = rules
+	} else if cfg != nil && cfg.ParserRules != nil {
+		opts.parserRules = cfg.ParserRules
+	}
+
+	servers := []lmsServer{srv}
+	if cfg != nil && len(cfg.Servers) > 0 {
+		var err error
+		servers, err = cfg.lmsServers()
+		if err != nil {
+			log.Fatalf("unable to load configuration: %v", err)
+		}
 	}
-	defer app.Stop()
 
-	err = app.Subscribe(topic, onMessage)
+	app, err := newApplication(&parameters, servers, opts)
 	if err != nil {
-		log.Fatalf("unable to subscribe to topic %v: %v", topic, err)
+		log.Fatalf("unable to start application: %v", err)
 	}
+	defer app.Stop()
 
 	err = app.Run()
 	if err != nil {
@@ -134,6 +376,15 @@ func main() {
 
 }
 
+// readSecret read a secret file as mounted by docker or kubernetes, trailing new lines are ignored
+func readSecret(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret file %v: %v", path, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
 func configureLogs(debug bool) {
 	log.SetFormatter(&log.TextFormatter{
 		DisableLevelTruncation: true,
@@ -148,7 +399,3 @@ func configureLogs(debug bool) {
 	}
 	log.SetReportCaller(false)
 }
-
-var onMessage = func(client MQTT.Client, message MQTT.Message) {
-
-}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"github.com/cyrilix/mqtt-tools/mqttTooling"
 	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"io/ioutil"
 	"os"
+	"sync"
 	"testing"
+	"time"
 )
 
 func Test_Cli(t *testing.T) {
@@ -21,26 +24,40 @@ func Test_Cli(t *testing.T) {
 	username := "user"
 	password := "password"
 	broker := "tcp://mqtt.example.com:1883"
-	server := "192.168.0.1:9000"
 	topic := "test"
+	secret, err := ioutil.TempFile("", "lms2mqtt")
+	if err != nil {
+		t.Fatalf("unable to create secret file: %v", err)
+	}
+	defer os.Remove(secret.Name())
+	if _, err := secret.WriteString("secret\n"); err != nil {
+		t.Fatalf("unable to write secret file: %v", err)
+	}
+	_ = secret.Close()
+
 	os.Args = []string{
 		"./lms2mqtt",
 		fmt.Sprintf("-mqtt-broker=%v", broker),
 		fmt.Sprintf("-mqtt-username=%v", username),
 		fmt.Sprintf("-mqtt-password=%v", password),
-		fmt.Sprintf("-address=%v", server),
 		fmt.Sprintf("-mqtt-topic=%v", topic),
 		"-debug",
+		"-ha-discovery",
+		"-position-interval=10s",
+		"-lms-username=admin",
+		"-lms-url=http://192.168.0.1:9000",
+		fmt.Sprintf("-lms-password-file=%v", secret.Name()),
 	}
-	newApplication = func(mcp *mqttTooling.MqttCliParameters, top, serverAddress string) (RunInterruptable, error) {
-		if serverAddress != server {
-			t.Errorf("bad server address: %v, wants %v", serverAddress, server)
+	newApplication = func(mcp *mqttTooling.MqttCliParameters, servers []lmsServer, opts options) (RunInterruptable, error) {
+		if len(servers) != 1 {
+			t.Fatalf("a single server is expected: %#v", servers)
 		}
+		srv := servers[0]
 		if broker != mcp.Broker {
 			t.Errorf("bad mqtt broker: %v, wants %v", mcp.Broker, broker)
 		}
-		if top != topic {
-			t.Errorf("bad mqtt topic: %v, wants %v", top, topic)
+		if srv.topic != topic {
+			t.Errorf("bad mqtt topic: %v, wants %v", srv.topic, topic)
 		}
 		if username != mcp.Username {
 			t.Errorf("bad mqtt user: %v, wants %v", mcp.Username, username)
@@ -48,6 +65,24 @@ func Test_Cli(t *testing.T) {
 		if password != mcp.Password {
 			t.Errorf("bad mqtt password: %v, wants %v", mcp.Password, password)
 		}
+		if !opts.haDiscovery {
+			t.Errorf("home assistant discovery should be enabled")
+		}
+		if opts.haDiscoveryPrefix != defaultHaDiscoveryPrefix {
+			t.Errorf("bad home assistant discovery prefix: %v, wants %v", opts.haDiscoveryPrefix, defaultHaDiscoveryPrefix)
+		}
+		if srv.username != "admin" || srv.password != "secret" {
+			t.Errorf("bad lms credentials: %v/%v", srv.username, srv.password)
+		}
+		if srv.url != "http://192.168.0.1:9000" {
+			t.Errorf("bad lms url: %v", srv.url)
+		}
+		if opts.artwork != artworkUrl {
+			t.Errorf("bad artwork mode: %v, wants %v", opts.artwork, artworkUrl)
+		}
+		if opts.positionInterval != 10*time.Second {
+			t.Errorf("bad position interval: %v", opts.positionInterval)
+		}
 		return mock, nil
 	}
 
@@ -100,3 +135,78 @@ func (a *appMock) Stop() {
 	a.stopCalled = true
 	a.isConnected = false
 }
+
+type publication struct {
+	topic    string
+	retained bool
+	payload  []byte
+}
+
+type clientMock struct {
+	mu           sync.Mutex
+	publications []publication
+	// subscribeErr is returned by subscriptions when defined
+	subscribeErr error
+}
+
+// errorToken is a completed token which failed
+type errorToken struct {
+	MQTT.DummyToken
+	err error
+}
+
+func (t *errorToken) Error() error { return t.err }
+
+func (c *clientMock) IsConnected() bool      { return true }
+func (c *clientMock) IsConnectionOpen() bool { return true }
+func (c *clientMock) Connect() MQTT.Token    { return &MQTT.DummyToken{} }
+func (c *clientMock) Disconnect(uint)        {}
+func (c *clientMock) Publish(topic string, _ byte, retained bool, payload interface{}) MQTT.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var content []byte
+	switch p := payload.(type) {
+	case []byte:
+		content = p
+	case string:
+		content = []byte(p)
+	}
+	c.publications = append(c.publications, publication{topic: topic, retained: retained, payload: content})
+	return &MQTT.DummyToken{}
+}
+func (c *clientMock) Subscribe(string, byte, MQTT.MessageHandler) MQTT.Token {
+	if c.subscribeErr != nil {
+		return &errorToken{err: c.subscribeErr}
+	}
+	return &MQTT.DummyToken{}
+}
+func (c *clientMock) SubscribeMultiple(map[string]byte, MQTT.MessageHandler) MQTT.Token {
+	return &MQTT.DummyToken{}
+}
+func (c *clientMock) Unsubscribe(...string) MQTT.Token        { return &MQTT.DummyToken{} }
+func (c *clientMock) AddRoute(string, MQTT.MessageHandler)    {}
+func (c *clientMock) OptionsReader() MQTT.ClientOptionsReader { return MQTT.ClientOptionsReader{} }
+
+func (c *clientMock) Publications() []publication {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]publication{}, c.publications...)
+}
+
+// Published return the last payload published on topic
+func (c *clientMock) Published(topic string) (publication, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.publications) - 1; i >= 0; i-- {
+		if c.publications[i].topic == topic {
+			return c.publications[i], true
+		}
+	}
+	return publication{}, false
+}
+
+func (c *clientMock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.publications = nil
+}
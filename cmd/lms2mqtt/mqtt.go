@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+)
+
+// connectMqtt works as mqttTooling.Connect but register a retained last will that flag the bridge as offline
+func connectMqtt(params *mqttTooling.MqttCliParameters, statusTopic string) (MQTT.Client, error) {
+	opts := MQTT.NewClientOptions().AddBroker(params.Broker)
+	opts.SetUsername(params.Username)
+	opts.SetPassword(params.Password)
+	opts.SetClientID(params.ClientId)
+	opts.SetAutoReconnect(true)
+	opts.SetCleanSession(params.Clean)
+	opts.SetWill(statusTopic, bridgeOffline, byte(params.Qos), true)
+	if params.HasTLSConfig() {
+		log.Printf("enable x509 authentication")
+		tlsConfig, err := params.TLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to configure tls parameters: %v", err)
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	client := MQTT.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("unable to connect to mqtt bus: %v", token.Error())
+	}
+	return client, nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/cyrilix/lms2mqtt/squeeze"
+)
+
+// publishPlaylist publish the player queue as a retained array
+func (a *application) publishPlaylist(p *squeeze.Playlist) {
+	tracks := make([]squeeze.PlaylistTrack, 0, len(p.Tracks))
+	for _, t := range p.Tracks {
+		if a.opts.artwork == artworkNone {
+			t.ArtworkUrl = ""
+		}
+		tracks = append(tracks, t)
+	}
+	a.publishJson(playlistTopic(a.topic, p.Player), true, tracks)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"reflect"
+	"testing"
+)
+
+func Test_publishPlaylist(t *testing.T) {
+	playlist := squeeze.Playlist{Player: "p1", Tracks: []squeeze.PlaylistTrack{
+		{Index: 0, Track: squeeze.Track{Title: "Tutti Frutti", ArtworkUrl: "http://lms:9000/music/a1/cover.jpg"}},
+		{Index: 1, Current: true, Track: squeeze.Track{Title: "Lucille", CurrentTime: 12}},
+	}}
+
+	cases := []struct {
+		name            string
+		artwork         string
+		expectedArtwork string
+	}{
+		{"With artwork", artworkUrl, "http://lms:9000/music/a1/cover.jpg"},
+		{"Without artwork", artworkNone, ""},
+	}
+	for _, c := range cases {
+		client := &clientMock{}
+		app := application{client: client, params: &mqttTooling.MqttCliParameters{}, topic: "lms", opts: options{artwork: c.artwork}}
+		app.publishPlaylist(&playlist)
+
+		p, ok := client.Published("lms/p1/playlist")
+		if !ok || !p.retained {
+			t.Fatalf("[%v] playlist should be published retained: %#v", c.name, client.Publications())
+		}
+		var tracks []squeeze.PlaylistTrack
+		if err := json.Unmarshal(p.payload, &tracks); err != nil {
+			t.Fatalf("[%v] bad payload %s: %v", c.name, p.payload, err)
+		}
+		expected := append([]squeeze.PlaylistTrack{}, playlist.Tracks...)
+		expected[0].ArtworkUrl = c.expectedArtwork
+		if !reflect.DeepEqual(tracks, expected) {
+			t.Errorf("[%v] bad tracks: %#v, wants %#v", c.name, tracks, expected)
+		}
+	}
+}
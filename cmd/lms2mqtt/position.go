@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// watchPositions periodically publish the position of playing players until done is closed
+func (a *application) watchPositions(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			a.publishPositions(a.server.PlayingPlayers())
+		}
+	}
+}
+
+func (a *application) publishPositions(ids []squeeze.PlayerId) {
+	for _, id := range ids {
+		p, err := a.server.Position(id)
+		if err != nil {
+			log.Warnf("unable to read position of player %v: %v", id, err)
+			continue
+		}
+		a.publishJson(positionTopic(a.topic, id), false, p)
+	}
+}
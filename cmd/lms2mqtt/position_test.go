@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"testing"
+)
+
+func Test_publishPositions(t *testing.T) {
+	lms := lmsMock{}
+	if err := lms.listen(); err != nil {
+		t.Fatalf("unable to start lms mock: %v", err)
+	}
+	defer lms.Close()
+
+	client := &clientMock{}
+	app := application{
+		client: client,
+		params: &mqttTooling.MqttCliParameters{},
+		topic:  "lms",
+		server: squeeze.New(lms.Addr()),
+	}
+	app.publishPositions([]squeeze.PlayerId{"p1", "p2"})
+
+	for _, id := range []squeeze.PlayerId{"p1", "p2"} {
+		pub, ok := client.Published(positionTopic("lms", id))
+		if !ok {
+			t.Errorf("[%v] no position published", id)
+			continue
+		}
+		if pub.retained {
+			t.Errorf("[%v] position shouldn't be retained", id)
+		}
+		var p squeeze.Position
+		if err := json.Unmarshal(pub.payload, &p); err != nil {
+			t.Errorf("[%v] bad payload %s: %v", id, pub.payload, err)
+		}
+		if p.Player != id || p.Timestamp == 0 {
+			t.Errorf("[%v] bad position: %#v", id, p)
+		}
+	}
+	if commands := lms.Commands(); len(commands) != 2 {
+		t.Errorf("bad commands: %#v", commands)
+	}
+}
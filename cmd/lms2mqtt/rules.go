@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"io/ioutil"
+)
+
+// loadParserRules read the ordered parser rules from a json file
+func loadParserRules(path string) ([]squeeze.ParserRule, error) {
+	var rules []squeeze.ParserRule
+	if err := loadJson(path, &rules); err != nil {
+		return nil, fmt.Errorf("unable to load parser rules: %v", err)
+	}
+	return rules, nil
+}
+
+// loadParsers read the regex parsers definitions from a json file
+func loadParsers(path string) ([]squeeze.RegexParserConfig, error) {
+	var parsers []squeeze.RegexParserConfig
+	if err := loadJson(path, &parsers); err != nil {
+		return nil, fmt.Errorf("unable to load parsers: %v", err)
+	}
+	return parsers, nil
+}
+
+// registerParsers make the regex parsers available to parser rules
+func registerParsers(configs []squeeze.RegexParserConfig) error {
+	for _, config := range configs {
+		p, err := squeeze.NewRegexParser(config)
+		if err != nil {
+			return err
+		}
+		squeeze.RegisterParser(config.Name, p)
+	}
+	return nil
+}
+
+func loadJson(path string, value interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read file %v: %v", path, err)
+	}
+	if err := json.Unmarshal(content, value); err != nil {
+		return fmt.Errorf("unable to decode file %v: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_loadParserRules(t *testing.T) {
+	cases := []struct {
+		name          string
+		content       string
+		expectedRules []squeeze.ParserRule
+		expectedErr   bool
+	}{
+		{"Rules",
+			`[{"field": "current_title", "regex": "(?i)^fip", "parser": "radiofrance"},
+			  {"field": "player", "prefix": "00:04:20", "parser": "default"}]`,
+			[]squeeze.ParserRule{
+				{Field: "current_title", Regex: "(?i)^fip", Parser: "radiofrance"},
+				{Field: "player", Prefix: "00:04:20", Parser: "default"},
+			},
+			false},
+		{"Empty", `[]`, []squeeze.ParserRule{}, false},
+		{"Invalid json", `{"field": "url"}`, nil, true},
+	}
+
+	for _, c := range cases {
+		f, err := ioutil.TempFile("", "rules")
+		if err != nil {
+			t.Fatalf("unable to create rules file: %v", err)
+		}
+		_, _ = f.WriteString(c.content)
+		_ = f.Close()
+
+		rules, err := loadParserRules(f.Name())
+		_ = os.Remove(f.Name())
+		if (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		if !c.expectedErr && !reflect.DeepEqual(rules, c.expectedRules) {
+			t.Errorf("[%v] bad rules: %#v, wants %#v", c.name, rules, c.expectedRules)
+		}
+	}
+
+	if _, err := loadParserRules("/nonexistent/rules.json"); err == nil {
+		t.Errorf("missing file should fail")
+	}
+}
+
+func Test_registerParsers(t *testing.T) {
+	f, err := ioutil.TempFile("", "parsers")
+	if err != nil {
+		t.Fatalf("unable to create parsers file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString(`[{"name": "artist-title", "patterns": [
+		{"field": "title", "regex": "^(?P<artist>.+?) - (?P<title>.+)$"}
+	]}]`)
+	_ = f.Close()
+
+	parsers, err := loadParsers(f.Name())
+	if err != nil {
+		t.Fatalf("unable to load parsers: %v", err)
+	}
+	if err := registerParsers(parsers); err != nil {
+		t.Fatalf("unable to register parsers: %v", err)
+	}
+	server := squeeze.New("127.0.0.1:9090")
+	if err := server.SetParserRules([]squeeze.ParserRule{{Field: "remote", Prefix: "1", Parser: "artist-title"}}); err != nil {
+		t.Errorf("registered parser should be usable by rules: %v", err)
+	}
+
+	err = registerParsers([]squeeze.RegexParserConfig{{Name: "bad", Patterns: []squeeze.FieldPattern{{Field: "title", Regex: "("}}}})
+	if err == nil {
+		t.Errorf("invalid parser should be rejected")
+	}
+}
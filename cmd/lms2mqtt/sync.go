@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/cyrilix/lms2mqtt/squeeze"
+)
+
+// publishSyncGroups publish the groups and the leader of each synchronized player, the leader of players
+// no longer synchronized is cleared
+func (a *application) publishSyncGroups(groups []squeeze.SyncGroup) {
+	a.publishJson(syncGroupsTopic(a.topic), true, groups)
+
+	leaders := make(map[squeeze.PlayerId]squeeze.PlayerId)
+	for _, g := range groups {
+		for _, id := range g.Members {
+			leaders[id] = g.Leader
+		}
+	}
+	for id, leader := range leaders {
+		if last, ok := a.leaders[id]; !ok || last != leader {
+			a.publish(leaderTopic(a.topic, id), true, []byte(leader))
+		}
+	}
+	for id := range a.leaders {
+		if _, ok := leaders[id]; !ok {
+			a.publish(leaderTopic(a.topic, id), true, []byte{})
+		}
+	}
+	a.leaders = leaders
+}
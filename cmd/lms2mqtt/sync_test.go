@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"github.com/cyrilix/mqtt-tools/mqttTooling"
+	"testing"
+)
+
+func Test_publishSyncGroups(t *testing.T) {
+	client := &clientMock{}
+	app := application{client: client, params: &mqttTooling.MqttCliParameters{}, topic: "lms"}
+
+	cases := []struct {
+		name            string
+		groups          []squeeze.SyncGroup
+		expectedLeaders map[string]string
+	}{
+		{"Group",
+			[]squeeze.SyncGroup{{Leader: "p1", Members: []squeeze.PlayerId{"p1", "p2"}}},
+			map[string]string{"lms/p1/leader": "p1", "lms/p2/leader": "p1"}},
+		{"New member",
+			[]squeeze.SyncGroup{{Leader: "p1", Members: []squeeze.PlayerId{"p1", "p2", "p3"}}},
+			map[string]string{"lms/p3/leader": "p1"}},
+		{"Unsync",
+			[]squeeze.SyncGroup{{Leader: "p1", Members: []squeeze.PlayerId{"p1", "p3"}}},
+			map[string]string{"lms/p2/leader": ""}},
+		{"No group", []squeeze.SyncGroup{}, map[string]string{"lms/p1/leader": "", "lms/p3/leader": ""}},
+	}
+	for _, c := range cases {
+		client.Reset()
+		app.publishSyncGroups(c.groups)
+
+		if _, ok := client.Published(syncGroupsTopic("lms")); !ok {
+			t.Errorf("[%v] sync groups should be published", c.name)
+		}
+		leaders := make(map[string]string)
+		for _, p := range client.Publications() {
+			if p.topic == syncGroupsTopic("lms") {
+				continue
+			}
+			if !p.retained {
+				t.Errorf("[%v] leader of %v should be retained", c.name, p.topic)
+			}
+			leaders[p.topic] = string(p.payload)
+		}
+		if len(leaders) != len(c.expectedLeaders) {
+			t.Errorf("[%v] bad leaders: %#v, wants %#v", c.name, leaders, c.expectedLeaders)
+			continue
+		}
+		for topic, leader := range c.expectedLeaders {
+			if l, ok := leaders[topic]; !ok || l != leader {
+				t.Errorf("[%v] bad leader on %v: %#v, wants %#v", c.name, topic, l, leader)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"strings"
+)
+
+const (
+	bridgeOnline  = "online"
+	bridgeOffline = "offline"
+)
+
+func playerTopic(prefix string, id squeeze.PlayerId, name string) string {
+	return fmt.Sprintf("%v/%v/%v", prefix, id, name)
+}
+
+func trackTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "track")
+}
+
+func stateTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "state")
+}
+
+func powerTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "power")
+}
+
+func mixerTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "mixer")
+}
+
+func artTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "art")
+}
+
+func playlistTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "playlist")
+}
+
+func positionTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "position")
+}
+
+func alarmsTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "alarms")
+}
+
+func alarmTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "alarm")
+}
+
+func sleepTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "sleep")
+}
+
+func leaderTopic(prefix string, id squeeze.PlayerId) string {
+	return playerTopic(prefix, id, "leader")
+}
+
+func commandTopic(prefix string) string {
+	return playerTopic(prefix, "+", "cmd")
+}
+
+func playersTopic(prefix string) string {
+	return fmt.Sprintf("%v/players", prefix)
+}
+
+func favoritesTopic(prefix string) string {
+	return fmt.Sprintf("%v/favorites", prefix)
+}
+
+func syncGroupsTopic(prefix string) string {
+	return fmt.Sprintf("%v/syncgroups", prefix)
+}
+
+func libraryRequestTopic(prefix string) string {
+	return fmt.Sprintf("%v/library/request", prefix)
+}
+
+func bridgeStatusTopic(prefix string) string {
+	return fmt.Sprintf("%v/bridge/status", prefix)
+}
+
+// playerFromTopic extract player id from a '<prefix>/<playerid>/<name>' topic
+func playerFromTopic(prefix, name, topic string) (squeeze.PlayerId, error) {
+	head := prefix + "/"
+	tail := "/" + name
+	if !strings.HasPrefix(topic, head) || !strings.HasSuffix(topic, tail) {
+		return "", fmt.Errorf("topic %v isn't a %v topic", topic, name)
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(topic, head), tail)
+	if id == "" || strings.Contains(id, "/") {
+		return "", fmt.Errorf("no player id in topic %v", topic)
+	}
+	return squeeze.PlayerId(id), nil
+}
+
+func serverStatusTopic(prefix string) string {
+	return fmt.Sprintf("%v/bridge/server", prefix)
+}
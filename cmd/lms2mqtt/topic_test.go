@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/cyrilix/lms2mqtt/squeeze"
+	"testing"
+)
+
+func Test_Topics(t *testing.T) {
+	cases := []struct {
+		name          string
+		topic         string
+		expectedTopic string
+	}{
+		{"Track", trackTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/track"},
+		{"State", stateTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/state"},
+		{"Power", powerTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/power"},
+		{"Mixer", mixerTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/mixer"},
+		{"Art", artTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/art"},
+		{"Playlist", playlistTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/playlist"},
+		{"Position", positionTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/position"},
+		{"Alarms", alarmsTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/alarms"},
+		{"Alarm", alarmTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/alarm"},
+		{"Sleep", sleepTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/sleep"},
+		{"Leader", leaderTopic("lms", "00:04:20:12:34:56"), "lms/00:04:20:12:34:56/leader"},
+		{"Command", commandTopic("lms"), "lms/+/cmd"},
+		{"Players", playersTopic("lms"), "lms/players"},
+		{"Favorites", favoritesTopic("lms"), "lms/favorites"},
+		{"Sync groups", syncGroupsTopic("lms"), "lms/syncgroups"},
+		{"Library request", libraryRequestTopic("lms"), "lms/library/request"},
+		{"Bridge status", bridgeStatusTopic("lms"), "lms/bridge/status"},
+		{"Server status", serverStatusTopic("lms"), "lms/bridge/server"},
+	}
+
+	for _, c := range cases {
+		if c.topic != c.expectedTopic {
+			t.Errorf("[%v] bad topic: %#v, wants %#v", c.name, c.topic, c.expectedTopic)
+		}
+	}
+}
+
+func Test_playerFromTopic(t *testing.T) {
+	cases := []struct {
+		name        string
+		topic       string
+		expectedId  squeeze.PlayerId
+		expectedErr bool
+	}{
+		{"Simple", "lms/00:04:20:12:34:56/cmd", "00:04:20:12:34:56", false},
+		{"Other prefix", "other/00:04:20:12:34:56/cmd", "", true},
+		{"No player", "lms//cmd", "", true},
+		{"Sub topic", "lms/a/b/cmd", "", true},
+		{"Not a command", "lms/00:04:20:12:34:56/track", "", true},
+	}
+
+	for _, c := range cases {
+		id, err := playerFromTopic("lms", "cmd", c.topic)
+		if (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		if id != c.expectedId {
+			t.Errorf("[%v] bad player id: %#v, wants %#v", c.name, id, c.expectedId)
+		}
+	}
+}
@@ -7,4 +7,5 @@ require (
 	github.com/eclipse/paho.mqtt.golang v1.2.0
 	github.com/konsorten/go-windows-terminal-sequences v1.0.3 // indirect
 	github.com/sirupsen/logrus v1.7.0
+	gopkg.in/yaml.v2 v2.4.0
 )
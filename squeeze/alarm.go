@@ -0,0 +1,218 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxAlarms = 100
+
+// Alarm is an alarm of a player, Time is 'HH:MM' and Days are week days from 0 (sunday) to 6.
+// Playlist is the url played by the alarm, or 'CURRENT_PLAYLIST'.
+type Alarm struct {
+	Id       string
+	Time     string
+	Days     []int
+	Enabled  bool
+	Repeat   bool
+	Volume   int
+	Playlist string
+}
+
+type Alarms struct {
+	Player PlayerId
+	Alarms []Alarm
+}
+
+// AlarmEvent is an alarm which starts to 'sound', is snoozed ('snooze'), wakes up after a snooze ('snooze_end')
+// or ends ('end'). Alarm is the alarm id, when known.
+type AlarmEvent struct {
+	Player PlayerId
+	Event  string
+	Alarm  string `json:",omitempty"`
+}
+
+// Alarms read all the alarms of the player, enabled or not
+func (s *Server) Alarms(id PlayerId) (*Alarms, error) {
+	values, err := s.query(id, "alarms", "0", strconv.Itoa(maxAlarms), "filter:all")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list alarms: %v", err)
+	}
+
+	alarms := Alarms{Player: id, Alarms: make([]Alarm, 0)}
+	for _, item := range parseTaggedItems(values, "id") {
+		alarms.Alarms = append(alarms.Alarms, parseAlarm(item))
+	}
+	return &alarms, nil
+}
+
+func parseAlarm(item map[string]string) Alarm {
+	seconds := parseInt(item["time"])
+	a := Alarm{
+		Id:       item["id"],
+		Time:     fmt.Sprintf("%02d:%02d", seconds/3600, seconds%3600/60),
+		Days:     make([]int, 0),
+		Enabled:  item["enabled"] == "1",
+		Repeat:   item["repeat"] == "1",
+		Volume:   parseInt(item["volume"]),
+		Playlist: item["url"],
+	}
+	for _, d := range strings.Split(item["dow"], ",") {
+		if day, err := strconv.Atoi(d); err == nil {
+			a.Days = append(a.Days, day)
+		}
+	}
+	return a
+}
+
+// AddAlarm create an alarm from 'time', 'days', 'enabled', 'repeat', 'volume' and 'playlist' settings,
+// time is required
+func (s *Server) AddAlarm(id PlayerId, settings map[string]string) error {
+	if _, ok := settings["time"]; !ok {
+		return fmt.Errorf("alarm time is required")
+	}
+	args, err := alarmArgs(settings)
+	if err != nil {
+		return err
+	}
+	return s.Command(id, append([]string{"alarm", "add"}, args...)...)
+}
+
+// UpdateAlarm change the given settings of the alarm, as AddAlarm
+func (s *Server) UpdateAlarm(id PlayerId, alarmId string, settings map[string]string) error {
+	args, err := alarmArgs(settings)
+	if err != nil {
+		return err
+	}
+	return s.Command(id, append([]string{"alarm", "update", "id:" + alarmId}, args...)...)
+}
+
+func (s *Server) EnableAlarm(id PlayerId, alarmId string) error {
+	return s.Command(id, "alarm", "update", "id:"+alarmId, "enabled:1")
+}
+
+func (s *Server) DisableAlarm(id PlayerId, alarmId string) error {
+	return s.Command(id, "alarm", "update", "id:"+alarmId, "enabled:0")
+}
+
+func (s *Server) DeleteAlarm(id PlayerId, alarmId string) error {
+	return s.Command(id, "alarm", "delete", "id:"+alarmId)
+}
+
+// alarmArgs convert alarm settings to the tagged parameters of the 'alarm' command, sorted by name
+func alarmArgs(settings map[string]string) ([]string, error) {
+	args := make([]string, 0, len(settings))
+	for name, value := range settings {
+		switch name {
+		case "time":
+			seconds, err := parseAlarmTime(value)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "time:"+strconv.Itoa(seconds))
+		case "days":
+			for _, d := range strings.Split(value, ",") {
+				if day, err := strconv.Atoi(d); err != nil || day < 0 || day > 6 {
+					return nil, fmt.Errorf("invalid alarm day \"%v\", must be between 0 (sunday) and 6", d)
+				}
+			}
+			args = append(args, "dow:"+value)
+		case "enabled", "repeat":
+			if value != "0" && value != "1" {
+				return nil, fmt.Errorf("invalid alarm %v value \"%v\", must be 0 or 1", name, value)
+			}
+			args = append(args, name+":"+value)
+		case "volume":
+			if v, err := strconv.Atoi(value); err != nil || v < 0 || v > 100 {
+				return nil, fmt.Errorf("invalid alarm volume \"%v\", must be between 0 and 100", value)
+			}
+			args = append(args, "volume:"+value)
+		case "playlist":
+			args = append(args, "url:"+value)
+		default:
+			return nil, fmt.Errorf("unknown alarm setting \"%v\"", name)
+		}
+	}
+	sort.Strings(args)
+	return args, nil
+}
+
+// parseAlarmTime return the seconds after midnight of a 'HH:MM' time
+func parseAlarmTime(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid alarm time \"%v\", must be HH:MM", value)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 23 {
+		return 0, fmt.Errorf("invalid alarm time \"%v\", must be HH:MM", value)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid alarm time \"%v\", must be HH:MM", value)
+	}
+	return hours*3600 + minutes*60, nil
+}
+
+func (s *Server) NotifyAlarmsChange() <-chan *Alarms {
+	return s.chanAlarms
+}
+
+func (s *Server) NotifyAlarmEvent() <-chan *AlarmEvent {
+	return s.chanAlarmEvents
+}
+
+// onAlarm handle '<playerid> alarm <action> ...' events: alarms are read again after changes,
+// sound, end and snooze events are notified
+func (s *Server) onAlarm(id PlayerId, fields []string) {
+	switch fields[2] {
+	case "add", "update", "delete", "enableall", "disableall":
+		s.refreshAlarms(id)
+	case "sound", "end", "snooze", "snooze_end":
+		e := AlarmEvent{Player: id, Event: fields[2]}
+		if len(fields) > 3 {
+			e.Alarm = unescapeFields(fields[3:4])[0]
+		}
+		s.notifyAlarmEvent(&e)
+	}
+}
+
+func (s *Server) notifyAlarmEvent(e *AlarmEvent) {
+	select {
+	case s.chanAlarmEvents <- e:
+	case <-s.done:
+	}
+}
+
+func (s *Server) refreshAlarms(id PlayerId) {
+	a, err := s.Alarms(id)
+	if err != nil {
+		log.Errorf("unable to read alarms of player %v: %v", id, err)
+		return
+	}
+	select {
+	case s.chanAlarms <- a:
+	case <-s.done:
+	}
+}
+
+// alarmStateEvent deduce the alarm event from a change of the 'alarm_state' status field
+// ('none', 'set', 'active' or 'snooze'), it is empty when nothing happened
+func alarmStateEvent(previous, current string) string {
+	switch {
+	case previous == current:
+		return ""
+	case current == "active" && previous == "snooze":
+		return "snooze_end"
+	case current == "active":
+		return "sound"
+	case current == "snooze":
+		return "snooze"
+	case previous == "active" || previous == "snooze":
+		return "end"
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package squeeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+const rawAlarms = "playerId alarms 0 100 filter%3Aall fade%3A1 count%3A2 " +
+	"id%3A8d1a2b dow%3A1%2C2%2C3%2C4%2C5 enabled%3A1 repeat%3A1 time%3A25200 volume%3A40 url%3ACURRENT_PLAYLIST " +
+	"id%3A3f4c5d dow%3A0%2C6 enabled%3A0 repeat%3A0 time%3A34200 volume%3A25 url%3Ahttp%3A%2F%2Ficecast.radiofrance.fr%2Ffip-midfi.mp3"
+
+func TestServer_Alarms(t *testing.T) {
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("playerId alarms 0 100 filter:all", rawAlarms)
+
+	server := New(squeezeMock.Addr())
+	alarms, err := server.Alarms(playerId)
+	if err != nil {
+		t.Fatalf("unable to list alarms: %v", err)
+	}
+	expected := Alarms{Player: playerId, Alarms: []Alarm{
+		{Id: "8d1a2b", Time: "07:00", Days: []int{1, 2, 3, 4, 5}, Enabled: true, Repeat: true, Volume: 40,
+			Playlist: "CURRENT_PLAYLIST"},
+		{Id: "3f4c5d", Time: "09:30", Days: []int{0, 6}, Volume: 25,
+			Playlist: "http://icecast.radiofrance.fr/fip-midfi.mp3"},
+	}}
+	if !reflect.DeepEqual(*alarms, expected) {
+		t.Errorf("bad alarms: %#v, wants %#v", *alarms, expected)
+	}
+}
+
+func TestServer_AlarmCommands(t *testing.T) {
+	cases := []struct {
+		name            string
+		command         func(s *Server) error
+		expectedCommand string
+	}{
+		{"Add", func(s *Server) error {
+			return s.AddAlarm(playerId, map[string]string{"time": "07:30", "days": "1,2", "volume": "40", "repeat": "1"})
+		}, "playerId alarm add dow:1%2C2 repeat:1 time:27000 volume:40"},
+		{"Add playlist", func(s *Server) error {
+			return s.AddAlarm(playerId, map[string]string{"time": "23:59", "playlist": "http://example.com/radio.mp3", "enabled": "0"})
+		}, "playerId alarm add enabled:0 time:86340 url:http:%2F%2Fexample.com%2Fradio.mp3"},
+		{"Add without time", func(s *Server) error { return s.AddAlarm(playerId, map[string]string{"volume": "40"}) }, ""},
+		{"Invalid time", func(s *Server) error { return s.AddAlarm(playerId, map[string]string{"time": "24:00"}) }, ""},
+		{"Invalid day", func(s *Server) error {
+			return s.AddAlarm(playerId, map[string]string{"time": "07:00", "days": "1,7"})
+		}, ""},
+		{"Invalid volume", func(s *Server) error { return s.UpdateAlarm(playerId, "8d1a2b", map[string]string{"volume": "101"}) }, ""},
+		{"Unknown setting", func(s *Server) error { return s.UpdateAlarm(playerId, "8d1a2b", map[string]string{"fade": "1"}) }, ""},
+		{"Update", func(s *Server) error { return s.UpdateAlarm(playerId, "8d1a2b", map[string]string{"time": "06:45"}) },
+			"playerId alarm update id:8d1a2b time:24300"},
+		{"Enable", func(s *Server) error { return s.EnableAlarm(playerId, "8d1a2b") }, "playerId alarm update id:8d1a2b enabled:1"},
+		{"Disable", func(s *Server) error { return s.DisableAlarm(playerId, "8d1a2b") }, "playerId alarm update id:8d1a2b enabled:0"},
+		{"Delete", func(s *Server) error { return s.DeleteAlarm(playerId, "8d1a2b") }, "playerId alarm delete id:8d1a2b"},
+	}
+
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	for _, c := range cases {
+		squeezeMock.ResetCommands()
+		err := c.command(server)
+		if (err != nil) != (c.expectedCommand == "") {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		commands := squeezeMock.Commands()
+		if c.expectedCommand == "" {
+			if len(commands) != 0 {
+				t.Errorf("[%v] no command expected: %#v", c.name, commands)
+			}
+			continue
+		}
+		if len(commands) != 1 || commands[0] != c.expectedCommand {
+			t.Errorf("[%v] bad commands: %#v, wants %#v", c.name, commands, c.expectedCommand)
+		}
+	}
+}
+
+func TestServer_onAlarmEvent(t *testing.T) {
+	cases := []struct {
+		name          string
+		line          string
+		expectedEvent AlarmEvent
+	}{
+		{"Sound", "playerId alarm sound 8d1a2b\n", AlarmEvent{Player: playerId, Event: "sound", Alarm: "8d1a2b"}},
+		{"Snooze", "playerId alarm snooze 8d1a2b\n", AlarmEvent{Player: playerId, Event: "snooze", Alarm: "8d1a2b"}},
+		{"Snooze end", "playerId alarm snooze_end\n", AlarmEvent{Player: playerId, Event: "snooze_end"}},
+		{"End", "playerId alarm end 8d1a2b\n", AlarmEvent{Player: playerId, Event: "end", Alarm: "8d1a2b"}},
+	}
+
+	server := New("127.0.0.1:1")
+	for _, c := range cases {
+		go server.processEventLine(c.line)
+		e := <-server.NotifyAlarmEvent()
+		if *e != c.expectedEvent {
+			t.Errorf("[%v] bad event: %#v, wants %#v", c.name, *e, c.expectedEvent)
+		}
+	}
+}
+
+func TestServer_onAlarmChanged(t *testing.T) {
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("playerId alarms 0 100 filter:all", rawAlarms)
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("playerId alarm update id%3A8d1a2b enabled%3A0\n")
+
+	alarms := <-server.NotifyAlarmsChange()
+	if alarms.Player != playerId || len(alarms.Alarms) != 2 {
+		t.Errorf("bad alarms: %#v", *alarms)
+	}
+}
+
+func Test_alarmStateEvent(t *testing.T) {
+	cases := []struct {
+		name          string
+		previous      string
+		current       string
+		expectedEvent string
+	}{
+		{"Unchanged", "active", "active", ""},
+		{"Set", "none", "set", ""},
+		{"Sound", "set", "active", "sound"},
+		{"Snooze", "active", "snooze", "snooze"},
+		{"Snooze end", "snooze", "active", "snooze_end"},
+		{"End", "active", "set", "end"},
+		{"End after snooze", "snooze", "none", "end"},
+	}
+
+	for _, c := range cases {
+		if e := alarmStateEvent(c.previous, c.current); e != c.expectedEvent {
+			t.Errorf("[%v] bad event: %#v, wants %#v", c.name, e, c.expectedEvent)
+		}
+	}
+}
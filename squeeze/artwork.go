@@ -0,0 +1,92 @@
+package squeeze
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultWebPort = "9000"
+	// maxArtworkSize protect the bridge from unexpected contents, covers are usually less than 1MB
+	maxArtworkSize = 10 << 20
+)
+
+// SetWebUrl define the web server url used for artworks, the port 9000 of the cli host is used by default
+func (s *Server) SetWebUrl(webUrl string) {
+	s.muState.Lock()
+	defer s.muState.Unlock()
+	s.webUrl = strings.TrimRight(webUrl, "/")
+}
+
+func (s *Server) getWebUrl() string {
+	s.muState.Lock()
+	defer s.muState.Unlock()
+	return s.webUrl
+}
+
+// defaultWebUrl return the url of the web server on the cli host
+func defaultWebUrl(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	return "http://" + net.JoinHostPort(host, defaultWebPort)
+}
+
+// artworkUrl return the absolute url of the track artwork: artwork_url of remote streams, as an external url or
+// a server path like '/imageproxy/...', or the cover of local tracks
+func (s *Server) artworkUrl(status *Status) string {
+	switch {
+	case status.ArtworkUrl != "":
+		u, err := url.Parse(status.ArtworkUrl)
+		if err == nil && u.IsAbs() {
+			return status.ArtworkUrl
+		}
+		return s.getWebUrl() + "/" + strings.TrimLeft(status.ArtworkUrl, "/")
+	case status.CoverId != "":
+		return fmt.Sprintf("%v/music/%v/cover.jpg", s.getWebUrl(), url.PathEscape(status.CoverId))
+	default:
+		return ""
+	}
+}
+
+// track build the track of the status with the parser selected by the rules
+func (s *Server) track(status *Status) *Track {
+	t := ParseTrack(s.metadataParser(status), status)
+	t.ArtworkUrl = s.artworkUrl(status)
+	return t
+}
+
+// Artwork download the image at artworkUrl, server credentials are sent to urls of the web server
+func (s *Server) Artwork(artworkUrl string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, artworkUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build artwork request: %v", err)
+	}
+	if username, password := s.credentials(); username != "" && strings.HasPrefix(artworkUrl, s.getWebUrl()+"/") {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download artwork %v: %v", artworkUrl, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download artwork %v: %v", artworkUrl, resp.Status)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxArtworkSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read artwork %v: %v", artworkUrl, err)
+	}
+	if len(content) > maxArtworkSize {
+		return nil, fmt.Errorf("artwork %v exceeds %d bytes", artworkUrl, maxArtworkSize)
+	}
+	return content, nil
+}
@@ -0,0 +1,73 @@
+package squeeze
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_artworkUrl(t *testing.T) {
+	cases := []struct {
+		name        string
+		server      *Server
+		status      Status
+		expectedUrl string
+	}{
+		{"Cover", New("192.168.1.2:9090"), Status{CoverId: "a1b2c3"}, "http://192.168.1.2:9000/music/a1b2c3/cover.jpg"},
+		{"Remote artwork", New("192.168.1.2:9090"),
+			Status{CoverId: "-94371624", ArtworkUrl: "https://example.com/cover.jpg"}, "https://example.com/cover.jpg"},
+		{"Proxied artwork", New("192.168.1.2:9090"),
+			Status{ArtworkUrl: "/imageproxy/https%3A%2F%2Fexample.com%2Fcover.jpg/image.jpg"},
+			"http://192.168.1.2:9000/imageproxy/https%3A%2F%2Fexample.com%2Fcover.jpg/image.jpg"},
+		{"Relative artwork", New("192.168.1.2:9090"), Status{ArtworkUrl: "html/images/radio.png"},
+			"http://192.168.1.2:9000/html/images/radio.png"},
+		{"Json-rpc", NewJsonRpc("https://lms.example.com/"), Status{CoverId: "a1b2c3"},
+			"https://lms.example.com/music/a1b2c3/cover.jpg"},
+		{"No artwork", New("192.168.1.2:9090"), Status{}, ""},
+	}
+
+	for _, c := range cases {
+		if u := c.server.artworkUrl(&c.status); u != c.expectedUrl {
+			t.Errorf("[%v] bad artwork url: %v, wants %v", c.name, u, c.expectedUrl)
+		}
+	}
+
+	server := New("lms:9090")
+	server.SetWebUrl("http://proxy/lms/")
+	if u := server.artworkUrl(&Status{CoverId: "a1"}); u != "http://proxy/lms/music/a1/cover.jpg" {
+		t.Errorf("bad artwork url with web url: %v", u)
+	}
+}
+
+func TestServer_Artwork(t *testing.T) {
+	image := []byte{0xff, 0xd8, 0xff, 0xe0}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/music/a1/cover.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write(image)
+	}))
+	defer ts.Close()
+
+	server := NewJsonRpc(ts.URL)
+	server.SetCredentials("user", "password")
+
+	content, err := server.Artwork(server.artworkUrl(&Status{CoverId: "a1"}))
+	if err != nil {
+		t.Fatalf("unable to download artwork: %v", err)
+	}
+	if !bytes.Equal(content, image) {
+		t.Errorf("bad artwork: %v", content)
+	}
+
+	if _, err := server.Artwork(ts.URL + "/music/unknown/cover.jpg"); err == nil {
+		t.Errorf("missing artwork should fail")
+	}
+}
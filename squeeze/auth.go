@@ -0,0 +1,87 @@
+package squeeze
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	loginTimeout = 5 * time.Second
+	// maxAuthFailures is the number of consecutive rejected logins before Listen stops, a server restart also closes
+	// the connection during login
+	maxAuthFailures = 3
+)
+
+// ErrAuthentication is returned when the server rejects the credentials, it closes the connection in this case
+var ErrAuthentication = errors.New("authentication failed")
+
+// SetCredentials configure the user and password sent with 'login' on each new connection, it must be called before Listen
+func (s *Server) SetCredentials(username, password string) {
+	s.muState.Lock()
+	s.username, s.password = username, password
+	s.muState.Unlock()
+	s.transport.setCredentials(username, password)
+	s.library.setCredentials(username, password)
+}
+
+func (s *Server) credentials() (string, string) {
+	s.muState.Lock()
+	defer s.muState.Unlock()
+	return s.username, s.password
+}
+
+// dial open a cli connection and log in when a username is defined
+func dial(address, username, password string) (io.ReadWriteCloser, error) {
+	conn, err := connect(address)
+	if err != nil {
+		return nil, err
+	}
+	if username == "" {
+		return conn, nil
+	}
+	if err := login(conn, username, password); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// login send the credentials and wait for the response without buffering, next lines belong to the caller
+func login(conn io.ReadWriteCloser, username, password string) error {
+	if d, ok := conn.(interface{ SetReadDeadline(time.Time) error }); ok {
+		if err := d.SetReadDeadline(time.Now().Add(loginTimeout)); err != nil {
+			return fmt.Errorf("unable to set login timeout: %v", err)
+		}
+		defer d.SetReadDeadline(time.Time{})
+	}
+
+	if _, err := fmt.Fprintf(conn, "login %s %s\n", escape(username), escape(password)); err != nil {
+		return fmt.Errorf("unable to send login command: %v", err)
+	}
+
+	var response strings.Builder
+	b := make([]byte, 1)
+	for {
+		_, err := conn.Read(b)
+		if err == io.EOF && response.Len() == 0 {
+			return fmt.Errorf("%w for user '%v'", ErrAuthentication, username)
+		}
+		if err == io.EOF {
+			return fmt.Errorf("connection closed during login")
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read login response: %v", err)
+		}
+		if b[0] == '\n' {
+			break
+		}
+		response.WriteByte(b[0])
+	}
+	if !strings.HasPrefix(response.String(), "login ") {
+		return fmt.Errorf("unexpected login response '%v'", strings.TrimRight(response.String(), "\r"))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package squeeze
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_Login(t *testing.T) {
+	cases := []struct {
+		name        string
+		username    string
+		password    string
+		expectedErr bool
+	}{
+		{"Valid credentials", "user", "p@ss word", false},
+		{"Bad password", "user", "other", true},
+		{"Missing credentials", "", "", true},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetLogin("user", "p@ss%20word")
+	squeezeMock.SetRawMode("play")
+
+	for _, c := range cases {
+		squeezeMock.ResetCommands()
+		server := New(squeezeMock.Addr())
+		server.SetCredentials(c.username, c.password)
+
+		mode, err := server.Mode(playerId)
+		if (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		if err == nil && mode != Playing {
+			t.Errorf("[%v] bad mode: %v", c.name, mode)
+		}
+		if c.username != "" && err != nil && !strings.Contains(err.Error(), ErrAuthentication.Error()) {
+			t.Errorf("[%v] authentication failure expected: %v", c.name, err)
+		}
+		if commands := squeezeMock.Commands(); c.username != "" && (len(commands) == 0 || !strings.HasPrefix(commands[0], "login user ")) {
+			t.Errorf("[%v] login should be sent first: %#v", c.name, commands)
+		}
+		_ = server.Close()
+	}
+}
+
+func TestServer_ListenAuthenticationFailure(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetLogin("user", "password")
+
+	server := New(squeezeMock.Addr())
+	server.minBackoff = 10 * time.Millisecond
+	server.SetCredentials("user", "bad")
+	defer server.Close()
+
+	chanListen := make(chan error)
+	go func() { chanListen <- server.Listen() }()
+	go func() {
+		for range server.NotifyConnectionChange() {
+		}
+	}()
+
+	select {
+	case err := <-chanListen:
+		if !errors.Is(err, ErrAuthentication) {
+			t.Errorf("authentication error expected: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("listen should stop on authentication failure")
+	}
+}
+
+func TestServer_ListenLoginInterrupted(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetLogin("user", "password")
+	squeezeMock.DropLogins(maxAuthFailures - 1)
+
+	server := New(squeezeMock.Addr())
+	server.minBackoff = 10 * time.Millisecond
+	server.maxBackoff = 20 * time.Millisecond
+	server.SetCredentials("user", "password")
+	defer server.Close()
+
+	chanListen := make(chan error, 1)
+	go func() { chanListen <- server.Listen() }()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case state := <-server.NotifyConnectionChange():
+			if state == Connected {
+				return
+			}
+		case <-server.NotifyPlayersChange():
+		case <-server.NotifyFavoritesChange():
+		case <-server.NotifySyncChange():
+		case <-server.NotifyAlarmsChange():
+		case <-server.NotifyAlarmEvent():
+		case <-server.NotifySleepChange():
+		case <-server.NotifyPowerChange():
+		case err := <-chanListen:
+			t.Fatalf("listen shouldn't stop on interrupted logins: %v", err)
+		case <-timeout:
+			t.Fatalf("listen should connect after interrupted logins")
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package squeeze
+
+import (
+	"bufio"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = 5 * time.Second
+)
+
+// cliClient share a single cli connection between concurrent requests.
+//
+// Requests are pipelined: they are written as soon as they are submitted and
+// responses are matched to pending requests by the echoed player id and command.
+// The server answers in order, so pending requests with the same key are served in FIFO order.
+// When a request times out, the connection is considered as broken and all pending requests fail.
+type cliClient struct {
+	address string
+	timeout time.Duration
+
+	mu       sync.Mutex
+	username string
+	password string
+	conn     io.ReadWriteCloser
+	pending  []*pendingRequest
+}
+
+type pendingRequest struct {
+	key      []string
+	conn     io.ReadWriteCloser
+	response chan cliResponse
+}
+
+type cliResponse struct {
+	line string
+	err  error
+}
+
+func newCliClient(address string) *cliClient {
+	return &cliClient{address: address, timeout: defaultRequestTimeout}
+}
+
+// roundTrip send a raw command line and return the raw response line
+func (c *cliClient) roundTrip(line string) (string, error) {
+	p, err := c.send(line)
+	if err != nil {
+		return "", err
+	}
+	return c.wait(p)
+}
+
+// query pipeline the requests and parse their responses
+func (c *cliClient) query(requests ...request) ([][]string, error) {
+	lines := make([]string, 0, len(requests))
+	for _, r := range requests {
+		lines = append(lines, formatCommand(r.player, r.args...))
+	}
+	responses, err := c.pipeline(lines...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]string, 0, len(responses))
+	for _, response := range responses {
+		v, err := parseResponse(response)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// pipeline send all command lines before waiting responses
+func (c *cliClient) pipeline(lines ...string) ([]string, error) {
+	pending := make([]*pendingRequest, 0, len(lines))
+	for _, line := range lines {
+		p, err := c.send(line)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+
+	responses := make([]string, 0, len(lines))
+	for _, p := range pending {
+		response, err := c.wait(p)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (c *cliClient) send(line string) (*pendingRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := dial(c.address, c.username, c.password)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to '%v' server: %w", c.address, err)
+		}
+		c.conn = conn
+		go c.read(conn)
+	}
+
+	p := &pendingRequest{key: requestKey(line), conn: c.conn, response: make(chan cliResponse, 1)}
+	c.pending = append(c.pending, p)
+	log.Debugf("send command '%v'", line)
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", line); err != nil {
+		go c.fail(p.conn, fmt.Errorf("unable to write command: %v", err))
+		return nil, fmt.Errorf("unable to write command '%v': %v", line, err)
+	}
+	return p, nil
+}
+
+func (c *cliClient) setCredentials(username, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.username, c.password = username, password
+}
+
+func (c *cliClient) wait(p *pendingRequest) (string, error) {
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-p.response:
+		return r.line, r.err
+	case <-timer.C:
+		c.fail(p.conn, fmt.Errorf("request timeout"))
+		return "", fmt.Errorf("no response to %v after %v", p.key, c.timeout)
+	}
+}
+
+func (c *cliClient) read(conn io.ReadWriteCloser) {
+	reader := bufio.NewReader(conn)
+	for {
+		rawLine, err := reader.ReadString('\n')
+		if err != nil {
+			c.fail(conn, fmt.Errorf("unable to read response: %v", err))
+			return
+		}
+		c.dispatch(strings.TrimRight(rawLine, "\r\n"))
+	}
+}
+
+func (c *cliClient) dispatch(line string) {
+	fields := unescapeFields(strings.Split(line, " "))
+
+	c.mu.Lock()
+	for i, p := range c.pending {
+		if hasPrefix(fields, p.key) {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			c.mu.Unlock()
+			p.response <- cliResponse{line: line}
+			return
+		}
+	}
+	c.mu.Unlock()
+	log.Warnf("ignore unexpected response '%v'", line)
+}
+
+// fail close the connection if it is still in use and abort its pending requests
+func (c *cliClient) fail(conn io.ReadWriteCloser, err error) {
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return
+	}
+	c.conn = nil
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	log.Debugf("close cli connection to %v: %v", c.address, err)
+	if err := conn.Close(); err != nil {
+		log.Debugf("unable to close cli connection to %v: %v", c.address, err)
+	}
+	for _, p := range pending {
+		p.response <- cliResponse{err: err}
+	}
+}
+
+func (c *cliClient) Close() error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn != nil {
+		c.fail(conn, fmt.Errorf("client closed"))
+	}
+	return nil
+}
+
+// requestKey return the unescaped fields that identify the response of a command: player id or command and the next field
+func requestKey(line string) []string {
+	fields := unescapeFields(strings.Split(line, " "))
+	key := make([]string, 0, 2)
+	for _, f := range fields {
+		if f == "?" || len(key) == 2 {
+			break
+		}
+		key = append(key, f)
+	}
+	return key
+}
+
+func unescapeFields(rawFields []string) []string {
+	fields := make([]string, 0, len(rawFields))
+	for _, rawField := range rawFields {
+		field, err := url.PathUnescape(rawField)
+		if err != nil {
+			field = rawField
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func hasPrefix(fields, prefix []string) bool {
+	if len(fields) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if fields[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package squeeze
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_requestKey(t *testing.T) {
+	cases := []struct {
+		name        string
+		line        string
+		expectedKey []string
+	}{
+		{"Player query", "00%3A04%3A20%3A12%3A34%3A56 artist ?", []string{"00:04:20:12:34:56", "artist"}},
+		{"Raw player id", "00:04:20:12:34:56 mixer volume ?", []string{"00:04:20:12:34:56", "mixer"}},
+		{"Server query", "player count ?", []string{"player", "count"}},
+		{"Single field query", "version ?", []string{"version"}},
+		{"Command", "players 0 2", []string{"players", "0"}},
+	}
+	for _, c := range cases {
+		if key := requestKey(c.line); !reflect.DeepEqual(key, c.expectedKey) {
+			t.Errorf("[%v] bad key: %#v, wants %#v", c.name, key, c.expectedKey)
+		}
+	}
+}
+
+func TestCliClient_SharedConnection(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetRawTrack(RawTrack{rawArtist: "Little%20Richard", rawTitle: "Lucille"})
+
+	server := New(squeezeMock.Addr())
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			track, err := server.CurrentTrack(playerId)
+			if err != nil {
+				t.Errorf("unable to read track: %v", err)
+				return
+			}
+			if track.Artist != "Little Richard" || track.Title != "Lucille" {
+				t.Errorf("bad track: %#v", *track)
+			}
+		}()
+	}
+	wg.Wait()
+
+	squeezeMock.muConns.Lock()
+	connections := len(squeezeMock.conns)
+	squeezeMock.muConns.Unlock()
+	if connections != 1 {
+		t.Errorf("bad connections count: %v, wants 1", connections)
+	}
+}
+
+func TestCliClient_Pipeline(t *testing.T) {
+	lms := newEchoServer(t)
+	defer lms.Close()
+
+	client := newCliClient(lms.Addr().String())
+	defer client.Close()
+
+	responses, err := client.pipeline("p1 artist ?", "p2 artist ?", "p1 title ?")
+	if err != nil {
+		t.Fatalf("unable to send requests: %v", err)
+	}
+	expected := []string{"p1 artist p1", "p2 artist p2", "p1 title p1"}
+	if !reflect.DeepEqual(responses, expected) {
+		t.Errorf("bad responses: %#v, wants %#v", responses, expected)
+	}
+}
+
+func TestCliClient_Timeout(t *testing.T) {
+	lms := newEchoServer(t)
+	defer lms.Close()
+
+	client := newCliClient(lms.Addr().String())
+	client.timeout = 50 * time.Millisecond
+	defer client.Close()
+
+	_, err := client.roundTrip("p1 silent ?")
+	if err == nil {
+		t.Errorf("an error is expected on timeout")
+	}
+
+	// A new connection is opened for the next requests
+	response, err := client.roundTrip("p1 artist ?")
+	if err != nil {
+		t.Errorf("unable to send request after timeout: %v", err)
+	}
+	if response != "p1 artist p1" {
+		t.Errorf("bad response: %#v", response)
+	}
+}
+
+func TestCliClient_ConnectionLost(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	client := newCliClient(squeezeMock.Addr())
+	defer client.Close()
+	if _, err := client.roundTrip("playerId artist ?"); err != nil {
+		t.Errorf("unable to send request: %v", err)
+	}
+
+	squeezeMock.DropConnections()
+
+	// Requests in flight when the connection is lost fail, next ones use a new connection
+	for i := 0; ; i++ {
+		_, err := client.roundTrip("playerId artist ?")
+		if err == nil {
+			break
+		}
+		if i >= 10 {
+			t.Fatalf("unable to send request after connection lost: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newEchoServer answer '<id> <cmd> ?' requests with '<id> <cmd> <id>', 'silent' requests never get response
+func newEchoServer(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					if fields[1] == "silent" {
+						continue
+					}
+					if _, err := conn.Write([]byte(fields[0] + " " + fields[1] + " " + fields[0] + "\r\n")); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return ln
+}
@@ -0,0 +1,267 @@
+package squeeze
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// cometdTimeout must exceed the time the server holds a '/meta/connect' long-poll without event
+	cometdTimeout = 2 * time.Minute
+)
+
+type cometdMessage struct {
+	Channel                  string          `json:"channel"`
+	Id                       string          `json:"id,omitempty"`
+	ClientId                 string          `json:"clientId,omitempty"`
+	Version                  string          `json:"version,omitempty"`
+	SupportedConnectionTypes []string        `json:"supportedConnectionTypes,omitempty"`
+	ConnectionType           string          `json:"connectionType,omitempty"`
+	Successful               *bool           `json:"successful,omitempty"`
+	Error                    string          `json:"error,omitempty"`
+	Data                     json.RawMessage `json:"data,omitempty"`
+}
+
+// cometdSubscription is the data of a '/slim/subscribe' message: the server sends the request result
+// on the response channel each time it changes
+type cometdSubscription struct {
+	Request  []interface{} `json:"request"`
+	Response string        `json:"response"`
+}
+
+// cometdClient is a bayeux long-polling session on the '/cometd' endpoint of the web server
+type cometdClient struct {
+	url      string
+	client   *http.Client
+	username string
+	password string
+	lastId   int64
+	clientId string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newCometdClient(baseUrl, username, password string) *cometdClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cometdClient{
+		url:      strings.TrimRight(baseUrl, "/") + "/cometd",
+		client:   &http.Client{Timeout: cometdTimeout},
+		username: username,
+		password: password,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (c *cometdClient) handshake() error {
+	messages, err := c.send(cometdMessage{
+		Channel:                  "/meta/handshake",
+		Version:                  "1.0",
+		SupportedConnectionTypes: []string{"long-polling"},
+	})
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		if m.Channel != "/meta/handshake" {
+			continue
+		}
+		if m.Successful == nil || !*m.Successful || m.ClientId == "" {
+			return fmt.Errorf("handshake refused: %v", m.Error)
+		}
+		c.clientId = m.ClientId
+		return nil
+	}
+	return fmt.Errorf("no handshake response")
+}
+
+// subscribe ask the server to publish the result of the request on '/<clientid>/slim/<name>',
+// the returned channel identify the following messages
+func (c *cometdClient) subscribe(player PlayerId, args []string, name string) (string, []cometdMessage, error) {
+	channel := fmt.Sprintf("/%v/slim/%v", c.clientId, name)
+	data, err := json.Marshal(cometdSubscription{
+		Request:  []interface{}{string(player), args},
+		Response: channel,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to marshal subscription: %v", err)
+	}
+	messages, err := c.send(cometdMessage{Channel: "/slim/subscribe", ClientId: c.clientId, Data: data})
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to subscribe to %v: %w", name, err)
+	}
+	return channel, messages, nil
+}
+
+// connect wait for the next messages, an unsuccessful connect means the session is lost
+func (c *cometdClient) connect() ([]cometdMessage, error) {
+	messages, err := c.send(cometdMessage{Channel: "/meta/connect", ClientId: c.clientId, ConnectionType: "long-polling"})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		if m.Channel == "/meta/connect" && m.Successful != nil && !*m.Successful {
+			return nil, fmt.Errorf("cometd session lost: %v", m.Error)
+		}
+	}
+	return messages, nil
+}
+
+func (c *cometdClient) send(messages ...cometdMessage) ([]cometdMessage, error) {
+	for i := range messages {
+		messages[i].Id = strconv.FormatInt(atomic.AddInt64(&c.lastId, 1), 10)
+	}
+	body, err := json.Marshal(messages)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal messages: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build request: %v", err)
+	}
+	req = req.WithContext(c.ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	log.Debugf("send cometd messages %s", body)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send messages to %v: %w", c.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w for user '%v'", ErrAuthentication, c.username)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected http status from %v: %v", c.url, resp.Status)
+	}
+	var response []cometdMessage
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("unable to decode messages: %v", err)
+	}
+	return response, nil
+}
+
+// Close abort the pending long-poll
+func (c *cometdClient) Close() error {
+	c.cancel()
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// cometdSession route the messages of a cometd session to the status tracker.
+// It is only used by the listening goroutine.
+type cometdSession struct {
+	*statusTracker
+	client *cometdClient
+
+	players       map[string]PlayerId
+	serverChannel string
+}
+
+// cometdOnce subscribe to server and players status with the cometd api and process updates until the session is lost
+func (s *Server) cometdOnce() (bool, error) {
+	s.setState(Connecting)
+	username, password := s.credentials()
+	client := newCometdClient(s.address, username, password)
+	if !s.setConn(client) {
+		return false, fmt.Errorf("server closed")
+	}
+	defer s.setConn(nil)
+	defer client.Close()
+
+	if err := client.handshake(); err != nil {
+		return false, fmt.Errorf("unable to handshake with %v: %w", s.address, err)
+	}
+	session := cometdSession{
+		statusTracker: newStatusTracker(s),
+		client:        client,
+		players:       make(map[string]PlayerId),
+	}
+	channel, messages, err := client.subscribe("", serverStatusArgs, "serverstatus")
+	if err != nil {
+		return false, err
+	}
+	session.serverChannel = channel
+	s.setState(Connected)
+	s.resync()
+	session.subscribePlayers(s.Players())
+	session.process(messages)
+
+	for {
+		messages, err := client.connect()
+		if err != nil {
+			return true, err
+		}
+		session.process(messages)
+	}
+}
+
+var serverStatusArgs = []string{"serverstatus", "0", "100", "subscribe:60"}
+
+func playerStatusArgs() []string {
+	return []string{"status", "-", "1", "tags:" + statusTags, "subscribe:0"}
+}
+
+// subscribePlayers subscribe to the status of connected players not yet subscribed
+func (c *cometdSession) subscribePlayers(players []Player) {
+	for _, p := range players {
+		if !p.Connected || c.subscribed(p.Id) {
+			continue
+		}
+		name := "playerstatus/" + strings.ReplaceAll(string(p.Id), ":", "")
+		channel, messages, err := c.client.subscribe(p.Id, playerStatusArgs(), name)
+		if err != nil {
+			log.Errorf("unable to subscribe to player %v status: %v", p.Id, err)
+			continue
+		}
+		c.players[channel] = p.Id
+		c.process(messages)
+	}
+}
+
+func (c *cometdSession) subscribed(id PlayerId) bool {
+	for _, p := range c.players {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cometdSession) process(messages []cometdMessage) {
+	for _, m := range messages {
+		if strings.HasPrefix(m.Channel, "/meta/") || m.Channel == "/slim/subscribe" || len(m.Data) == 0 {
+			continue
+		}
+		result, err := decodeJsonResult(m.Data)
+		if err != nil {
+			log.Warnf("unable to decode data of message on %v: %v", m.Channel, err)
+			continue
+		}
+
+		if m.Channel == c.serverChannel {
+			c.subscribePlayers(c.onServerStatus(jsonRpcFields(request{args: serverStatusArgs}, result)))
+			continue
+		}
+		id, ok := c.players[m.Channel]
+		if !ok {
+			log.Debugf("ignore message on %v", m.Channel)
+			continue
+		}
+		c.onPlayerStatus(id, jsonRpcFields(request{player: id, args: playerStatusArgs()}, result))
+	}
+}
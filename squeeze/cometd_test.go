@@ -0,0 +1,225 @@
+package squeeze
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServer_CometdEvents(t *testing.T) {
+	cometd := newCometdMock()
+	mux := http.NewServeMux()
+	mux.Handle("/jsonrpc.js", newJsonRpcMock("", ""))
+	mux.Handle("/cometd", cometd)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	server := NewJsonRpc(ts.URL)
+	chanListen := make(chan error)
+	go func() { chanListen <- server.Listen() }()
+
+	var tracks []*PlayerTrack
+	var mixers []*Mixer
+	var players [][]Player
+	var states []*Playback
+	var playlists []*Playlist
+	// wait read notifications until the condition is met
+	wait := func(name string, condition func() bool) {
+		timeout := time.After(2 * time.Second)
+		for !condition() {
+			select {
+			case <-server.NotifyConnectionChange():
+			case p := <-server.NotifyPlayersChange():
+				players = append(players, p)
+			case p := <-server.NotifyPlaybackChange():
+				states = append(states, p)
+			case tr := <-server.NotifyTrackChange():
+				tracks = append(tracks, tr)
+			case m := <-server.NotifyMixerChange():
+				mixers = append(mixers, m)
+			case p := <-server.NotifyPlaylistChange():
+				playlists = append(playlists, p)
+			case <-server.NotifyFavoritesChange():
+			case <-server.NotifySyncChange():
+			case <-server.NotifyAlarmsChange():
+			case <-server.NotifyAlarmEvent():
+			case <-server.NotifySleepChange():
+			case <-server.NotifyPowerChange():
+			case <-time.After(10 * time.Millisecond):
+			case <-timeout:
+				t.Fatalf("%v not reached", name)
+			}
+		}
+	}
+
+	wait("player subscription", func() bool { return cometd.Channel("status") != "" && len(tracks) > 0 })
+	if ch := cometd.Channel("serverstatus"); ch != "/c1/slim/serverstatus" {
+		t.Errorf("bad serverstatus channel: %v", ch)
+	}
+	if ch := cometd.Channel("status"); ch != "/c1/slim/playerstatus/playerId" {
+		t.Errorf("bad playerstatus channel: %v", ch)
+	}
+
+	cometd.Push(cometd.Channel("status"), `{"mode":"pause","time":12,"mixer volume":-30,"playlist_loop":[`+
+		`{"playlist index":0,"title":"Lucille","artist":"Little Richard","duration":150}]}`)
+	wait("track change", func() bool { return tracks[len(tracks)-1].Title == "Lucille" })
+	last := tracks[len(tracks)-1]
+	if last.Player != playerId || last.Artist != "Little Richard" || last.CurrentTime != 12 || last.State != Paused {
+		t.Errorf("bad track: %#v", *last)
+	}
+	wait("mixer change", func() bool { return len(mixers) > 0 && mixers[len(mixers)-1].Volume == 30 })
+	if m := mixers[len(mixers)-1]; !m.Muted || m.Player != playerId {
+		t.Errorf("bad mixer: %#v", *m)
+	}
+	wait("playback change", func() bool { return len(states) > 0 && states[len(states)-1].State == Paused })
+
+	// Playlists of resync and of the first status
+	wait("first playlists", func() bool { return len(playlists) >= 2 })
+
+	// Only the elapsed time changes
+	count, playlistCount := len(tracks), len(playlists)
+	cometd.Push(cometd.Channel("status"), `{"mode":"pause","time":13,"mixer volume":-30,"playlist_loop":[`+
+		`{"playlist index":0,"title":"Lucille","artist":"Little Richard","duration":150}]}`)
+	cometd.Push(cometd.Channel("serverstatus"), `{"player count":2,"players_loop":[`+
+		`{"playerindex":"0","playerid":"playerId","name":"Kitchen","connected":1},`+
+		`{"playerindex":"1","playerid":"other","name":"Bedroom","connected":0}]}`)
+	wait("players change", func() bool { return len(players) > 0 && len(players[len(players)-1]) == 2 })
+	if len(tracks) != count {
+		t.Errorf("unchanged track notified: %#v", *tracks[len(tracks)-1])
+	}
+	if len(playlists) != playlistCount {
+		t.Errorf("unchanged playlist notified")
+	}
+
+	cometd.Push(cometd.Channel("status"), `{"mode":"pause","time":14,"mixer volume":-30,"playlist_timestamp":"1602864000.1",`+
+		`"playlist_loop":[{"playlist index":0,"title":"Lucille","artist":"Little Richard","duration":150}]}`)
+	wait("playlist change", func() bool { return len(playlists) > playlistCount })
+
+	// The track is notified again with the new playback state
+	cometd.Push(cometd.Channel("status"), `{"mode":"play","time":15,"mixer volume":-30,"playlist_timestamp":"1602864000.1",`+
+		`"playlist_loop":[{"playlist index":0,"title":"Lucille","artist":"Little Richard","duration":150}]}`)
+	wait("track state change", func() bool { return tracks[len(tracks)-1].State == Playing })
+
+	if err := server.Close(); err != nil {
+		t.Errorf("unable to close server: %v", err)
+	}
+	go func() {
+		for range server.NotifyConnectionChange() {
+		}
+	}()
+	select {
+	case err := <-chanListen:
+		if err != nil {
+			t.Errorf("unexpected listen error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("listen should stop on close")
+	}
+}
+
+func TestServer_CometdSubscribeAuthentication(t *testing.T) {
+	cometd := newCometdMock()
+	mux := http.NewServeMux()
+	mux.Handle("/jsonrpc.js", newJsonRpcMock("", ""))
+	// The handshake is accepted, subscriptions are rejected
+	mux.HandleFunc("/cometd", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if bytes.Contains(body, []byte("/slim/subscribe")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		cometd.ServeHTTP(w, r)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	server := NewJsonRpc(ts.URL)
+	server.minBackoff = 10 * time.Millisecond
+	defer server.Close()
+	chanListen := make(chan error)
+	go func() { chanListen <- server.Listen() }()
+	go func() {
+		for range server.NotifyConnectionChange() {
+		}
+	}()
+
+	select {
+	case err := <-chanListen:
+		if !errors.Is(err, ErrAuthentication) {
+			t.Errorf("authentication error expected: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("listen should stop on authentication failure")
+	}
+}
+
+// cometdMock is a minimal bayeux server, messages pushed are delivered on the next '/meta/connect'
+type cometdMock struct {
+	events chan cometdMessage
+
+	mu            sync.Mutex
+	subscriptions map[string]string
+}
+
+func newCometdMock() *cometdMock {
+	return &cometdMock{events: make(chan cometdMessage, 10), subscriptions: make(map[string]string)}
+}
+
+func (c *cometdMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var messages []cometdMessage
+	if err := json.NewDecoder(r.Body).Decode(&messages); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	successful := true
+	response := make([]cometdMessage, 0)
+	for _, m := range messages {
+		switch m.Channel {
+		case "/meta/handshake":
+			response = append(response, cometdMessage{Channel: m.Channel, ClientId: "c1", Successful: &successful})
+		case "/slim/subscribe":
+			var sub struct {
+				Request  []json.RawMessage `json:"request"`
+				Response string            `json:"response"`
+			}
+			var args []string
+			if json.Unmarshal(m.Data, &sub) != nil || len(sub.Request) != 2 || json.Unmarshal(sub.Request[1], &args) != nil {
+				http.Error(w, "bad subscription", http.StatusBadRequest)
+				return
+			}
+			c.mu.Lock()
+			c.subscriptions[args[0]] = sub.Response
+			c.mu.Unlock()
+			response = append(response, cometdMessage{Channel: m.Channel, Successful: &successful})
+		case "/meta/connect":
+			response = append(response, cometdMessage{Channel: m.Channel, Successful: &successful})
+			select {
+			case e := <-c.events:
+				response = append(response, e)
+			case <-time.After(50 * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Channel return the response channel of the subscription to the command
+func (c *cometdMock) Channel(command string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscriptions[command]
+}
+
+func (c *cometdMock) Push(channel, data string) {
+	c.events <- cometdMessage{Channel: channel, Data: json.RawMessage(data)}
+}
@@ -0,0 +1,106 @@
+package squeeze
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func (s *Server) Play(id PlayerId) error {
+	return s.Command(id, "play")
+}
+
+func (s *Server) Pause(id PlayerId) error {
+	return s.Command(id, "pause", "1")
+}
+
+func (s *Server) Toggle(id PlayerId) error {
+	return s.Command(id, "pause")
+}
+
+func (s *Server) Stop(id PlayerId) error {
+	return s.Command(id, "stop")
+}
+
+func (s *Server) Next(id PlayerId) error {
+	return s.Command(id, "playlist", "index", "+1")
+}
+
+func (s *Server) Previous(id PlayerId) error {
+	return s.Command(id, "playlist", "index", "-1")
+}
+
+func (s *Server) PowerOn(id PlayerId) error {
+	return s.Command(id, "power", "1")
+}
+
+func (s *Server) PowerOff(id PlayerId) error {
+	return s.Command(id, "power", "0")
+}
+
+func (s *Server) TogglePower(id PlayerId) error {
+	return s.Command(id, "power")
+}
+
+// Command send a raw cli command to the player and wait for the server acknowledgment
+func (s *Server) Command(id PlayerId, args ...string) error {
+	_, err := s.query(id, args...)
+	if err != nil {
+		return fmt.Errorf("unable to send command %v to player %v: %v", args, id, err)
+	}
+	return nil
+}
+
+// query send a single request on the server transport, id is empty for server wide requests
+func (s *Server) query(id PlayerId, args ...string) ([]string, error) {
+	responses, err := s.transport.query(request{player: id, args: args})
+	if err != nil {
+		return nil, err
+	}
+	return responses[0], nil
+}
+
+// queryAll send many requests at once on the server transport
+func (s *Server) queryAll(id PlayerId, requests ...[]string) ([][]string, error) {
+	reqs := make([]request, 0, len(requests))
+	for _, args := range requests {
+		reqs = append(reqs, request{player: id, args: args})
+	}
+	return s.transport.query(reqs...)
+}
+
+// escape a cli argument, the query marker is kept as is
+func escape(arg string) string {
+	if arg == "?" {
+		return arg
+	}
+	return url.PathEscape(arg)
+}
+
+func formatCommand(id PlayerId, args ...string) string {
+	escapedArgs := make([]string, 0, len(args)+1)
+	if id != "" {
+		escapedArgs = append(escapedArgs, escape(string(id)))
+	}
+	for _, arg := range args {
+		escapedArgs = append(escapedArgs, escape(arg))
+	}
+	return strings.Join(escapedArgs, " ")
+}
+
+// parseResponse return the unescaped fields of a server response
+func parseResponse(rawLine string) ([]string, error) {
+	line := strings.ReplaceAll(rawLine, "\n", "")
+	line = strings.ReplaceAll(line, "\r", "")
+
+	rawValues := strings.Split(line, " ")
+	values := make([]string, 0, len(rawValues))
+	for _, rawValue := range rawValues {
+		value, err := url.PathUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unescape value \"%v\": %v", rawValue, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
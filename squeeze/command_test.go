@@ -0,0 +1,63 @@
+package squeeze
+
+import (
+	"testing"
+)
+
+func TestServer_Command(t *testing.T) {
+	cases := []struct {
+		name            string
+		command         func(s *Server, id PlayerId) error
+		expectedCommand string
+	}{
+		{"Play", (*Server).Play, "playerId play"},
+		{"Pause", (*Server).Pause, "playerId pause 1"},
+		{"Toggle", (*Server).Toggle, "playerId pause"},
+		{"Stop", (*Server).Stop, "playerId stop"},
+		{"Next", (*Server).Next, "playerId playlist index +1"},
+		{"Previous", (*Server).Previous, "playerId playlist index -1"},
+		{"Power on", (*Server).PowerOn, "playerId power 1"},
+		{"Power off", (*Server).PowerOff, "playerId power 0"},
+		{"Toggle power", (*Server).TogglePower, "playerId power"},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	for _, c := range cases {
+		squeezeMock.ResetCommands()
+
+		err := c.command(server, playerId)
+		if err != nil {
+			t.Errorf("[%v] unable to send command: %v", c.name, err)
+		}
+		commands := squeezeMock.Commands()
+		if len(commands) != 1 || commands[0] != c.expectedCommand {
+			t.Errorf("[%v] bad commands sent: %#v, wants %#v", c.name, commands, []string{c.expectedCommand})
+		}
+	}
+}
+
+func TestServer_CommandEscaping(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	err = server.Command(playerId, "playlist", "play", "Morning playlist")
+	if err != nil {
+		t.Errorf("unable to send command: %v", err)
+	}
+	expected := "playerId playlist play Morning%20playlist"
+	if commands := squeezeMock.Commands(); len(commands) != 1 || commands[0] != expected {
+		t.Errorf("bad commands sent: %#v, wants %#v", commands, []string{expected})
+	}
+}
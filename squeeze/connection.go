@@ -0,0 +1,74 @@
+package squeeze
+
+import (
+	"io"
+	"math/rand"
+	"time"
+)
+
+type ConnectionState string
+
+const (
+	Disconnected = ConnectionState("disconnected")
+	Connecting   = ConnectionState("connecting")
+	Connected    = ConnectionState("connected")
+)
+
+const (
+	defaultMinBackoff = 1 * time.Second
+	defaultMaxBackoff = 1 * time.Minute
+)
+
+func (s *Server) State() ConnectionState {
+	s.muState.Lock()
+	defer s.muState.Unlock()
+	return s.state
+}
+
+func (s *Server) NotifyConnectionChange() <-chan ConnectionState {
+	return s.chanState
+}
+
+func (s *Server) setState(state ConnectionState) {
+	s.muState.Lock()
+	changed := s.state != state
+	s.state = state
+	s.muState.Unlock()
+
+	if !changed {
+		return
+	}
+	select {
+	case s.chanState <- state:
+	case <-s.done:
+	}
+}
+
+// setConn register the events connection to close on shutdown, return false if server is already closed
+func (s *Server) setConn(conn io.Closer) bool {
+	s.muState.Lock()
+	defer s.muState.Unlock()
+	if conn != nil && s.isClosed() {
+		return false
+	}
+	s.conn = conn
+	return true
+}
+
+func (s *Server) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// jitter return a random delay between the half and the whole backoff duration
+func jitter(backoff time.Duration) time.Duration {
+	half := int64(backoff) / 2
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
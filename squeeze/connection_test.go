@@ -0,0 +1,120 @@
+package squeeze
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServer_Reconnect(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("player count ?", "player count 0")
+	squeezeMock.SetResponse("players 0 0", "players 0 0 count%3A0")
+
+	server := New(squeezeMock.Addr())
+	server.minBackoff = 10 * time.Millisecond
+	server.maxBackoff = 20 * time.Millisecond
+
+	chanListen := make(chan error)
+	go func() { chanListen <- server.Listen() }()
+
+	waitState := func(expected ConnectionState) {
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case state := <-server.NotifyConnectionChange():
+				if state == expected {
+					return
+				}
+			case <-server.NotifyPlayersChange():
+			case <-server.NotifyFavoritesChange():
+			case <-server.NotifySyncChange():
+			case <-server.NotifyAlarmsChange():
+			case <-server.NotifyAlarmEvent():
+			case <-server.NotifySleepChange():
+			case <-server.NotifyPowerChange():
+			case <-timeout:
+				t.Fatalf("state %v not reached, current state: %v", expected, server.State())
+			}
+		}
+	}
+
+	waitState(Connected)
+	// Wait events connection is registered by mock before dropping it
+	for !contains(squeezeMock.Commands(), "listen 1") {
+		time.Sleep(time.Millisecond)
+	}
+	squeezeMock.DropConnections()
+	waitState(Disconnected)
+	waitState(Connected)
+
+	if server.State() != Connected {
+		t.Errorf("bad state: %v, wants %v", server.State(), Connected)
+	}
+
+	if err := server.Close(); err != nil {
+		t.Errorf("unable to close server: %v", err)
+	}
+	select {
+	case err := <-chanListen:
+		if err != nil {
+			t.Errorf("unexpected listen error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("listen not stopped after close")
+	}
+	if _, ok := <-server.NotifyTrackChange(); ok {
+		t.Errorf("track channel should be closed")
+	}
+}
+
+func TestServer_ListenRetryConnection(t *testing.T) {
+	server := New("127.0.0.1:1")
+	server.minBackoff = 10 * time.Millisecond
+	server.maxBackoff = 20 * time.Millisecond
+
+	chanListen := make(chan error)
+	go func() { chanListen <- server.Listen() }()
+
+	attempts := 0
+	for attempts < 3 {
+		state := <-server.NotifyConnectionChange()
+		if state == Connecting {
+			attempts++
+		}
+		if state == Connected {
+			t.Fatalf("unexpected connection")
+		}
+	}
+
+	if err := server.Close(); err != nil {
+		t.Errorf("unable to close server: %v", err)
+	}
+	select {
+	case <-chanListen:
+	case <-time.After(2 * time.Second):
+		t.Errorf("listen not stopped after close")
+	}
+}
+
+func Test_jitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second)
+		if d < 500*time.Millisecond || d > time.Second {
+			t.Errorf("bad jitter value: %v", d)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxFavorites      = 500
+	maxFavoritesDepth = 5
+)
+
+type Favorite struct {
+	Id    string
+	Name  string
+	Type  string
+	Audio bool
+	Items []Favorite `json:",omitempty"`
+}
+
+// Favorites read the favorites tree, folders are read up to a depth of 5.
+// The tree is kept to resolve the favorites to play until it is read again on 'favorites changed' events.
+func (s *Server) Favorites() ([]Favorite, error) {
+	favorites, err := s.favoriteItems("", 0)
+	if err != nil {
+		return nil, err
+	}
+	s.muFavorites.Lock()
+	s.favorites = favorites
+	s.muFavorites.Unlock()
+	return favorites, nil
+}
+
+// knownFavorites return the last favorites tree read, it is only read when unknown
+func (s *Server) knownFavorites() ([]Favorite, error) {
+	s.muFavorites.Lock()
+	favorites := s.favorites
+	s.muFavorites.Unlock()
+	if favorites != nil {
+		return favorites, nil
+	}
+	return s.Favorites()
+}
+
+func (s *Server) favoriteItems(folderId string, depth int) ([]Favorite, error) {
+	args := []string{"favorites", "items", "0", strconv.Itoa(maxFavorites), "want_url:1"}
+	if folderId != "" {
+		args = append(args, "item_id:"+folderId)
+	}
+	values, err := s.query("", args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list favorites: %v", err)
+	}
+
+	favorites := make([]Favorite, 0)
+	for _, item := range parseTaggedItems(values, "id") {
+		f := Favorite{Id: item["id"], Name: item["name"], Type: item["type"], Audio: item["isaudio"] == "1"}
+		if item["hasitems"] == "1" && depth+1 < maxFavoritesDepth {
+			f.Items, err = s.favoriteItems(f.Id, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		favorites = append(favorites, f)
+	}
+	return favorites, nil
+}
+
+// PlayFavorite play the favorite with the id, or else the first one with the name ignoring case
+func (s *Server) PlayFavorite(id PlayerId, favorite string) error {
+	favorites, err := s.knownFavorites()
+	if err != nil {
+		return err
+	}
+	f, ok := findFavorite(favorites, func(f Favorite) bool { return f.Id == favorite })
+	if !ok {
+		f, ok = findFavorite(favorites, func(f Favorite) bool { return strings.EqualFold(f.Name, favorite) })
+	}
+	if !ok {
+		return fmt.Errorf("no favorite '%v'", favorite)
+	}
+	if !f.Audio {
+		return fmt.Errorf("favorite '%v' isn't playable", f.Name)
+	}
+	return s.Command(id, "favorites", "playlist", "play", "item_id:"+f.Id)
+}
+
+func findFavorite(favorites []Favorite, match func(Favorite) bool) (Favorite, bool) {
+	for _, f := range favorites {
+		if match(f) {
+			return f, true
+		}
+		if found, ok := findFavorite(f.Items, match); ok {
+			return found, true
+		}
+	}
+	return Favorite{}, false
+}
+
+func (s *Server) NotifyFavoritesChange() <-chan []Favorite {
+	return s.chanFavorites
+}
+
+func (s *Server) refreshFavorites() {
+	favorites, err := s.Favorites()
+	if err != nil {
+		log.Errorf("unable to read favorites: %v", err)
+		return
+	}
+	select {
+	case s.chanFavorites <- favorites:
+	case <-s.done:
+	}
+}
@@ -0,0 +1,113 @@
+package squeeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	rawFavorites = "favorites items 0 500 want_url%3A1 title%3AFavorites id%3A4c3b5f7e.0 name%3AFIP type%3Aaudio " +
+		"isaudio%3A1 hasitems%3A0 id%3A4c3b5f7e.1 name%3ARadios type%3Aplaylist isaudio%3A0 hasitems%3A1 count%3A2"
+	rawFavoritesFolder = "favorites items 0 500 want_url%3A1 item_id%3A4c3b5f7e.1 title%3ARadios " +
+		"id%3A4c3b5f7e.1.0 name%3AMorning%20playlist type%3Aaudio isaudio%3A1 hasitems%3A0 count%3A1"
+)
+
+func newFavoritesMock(t *testing.T) *ConnMock {
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	squeezeMock.SetResponse("favorites items 0 500 want_url:1", rawFavorites)
+	squeezeMock.SetResponse("favorites items 0 500 want_url:1 item_id:4c3b5f7e.1", rawFavoritesFolder)
+	return &squeezeMock
+}
+
+func TestServer_Favorites(t *testing.T) {
+	squeezeMock := newFavoritesMock(t)
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	favorites, err := server.Favorites()
+	if err != nil {
+		t.Fatalf("unable to list favorites: %v", err)
+	}
+	expected := []Favorite{
+		{Id: "4c3b5f7e.0", Name: "FIP", Type: "audio", Audio: true},
+		{Id: "4c3b5f7e.1", Name: "Radios", Type: "playlist", Items: []Favorite{
+			{Id: "4c3b5f7e.1.0", Name: "Morning playlist", Type: "audio", Audio: true},
+		}},
+	}
+	if !reflect.DeepEqual(favorites, expected) {
+		t.Errorf("bad favorites: %#v, wants %#v", favorites, expected)
+	}
+}
+
+func TestServer_PlayFavorite(t *testing.T) {
+	cases := []struct {
+		name            string
+		favorite        string
+		expectedCommand string
+		expectedErr     bool
+	}{
+		{"By id", "4c3b5f7e.0", "playerId favorites playlist play item_id:4c3b5f7e.0", false},
+		{"By name", "fip", "playerId favorites playlist play item_id:4c3b5f7e.0", false},
+		{"In folder", "Morning Playlist", "playerId favorites playlist play item_id:4c3b5f7e.1.0", false},
+		{"Folder", "radios", "", true},
+		{"Unknown", "France Inter", "", true},
+	}
+
+	squeezeMock := newFavoritesMock(t)
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+	if _, err := server.Favorites(); err != nil {
+		t.Fatalf("unable to list favorites: %v", err)
+	}
+
+	for _, c := range cases {
+		squeezeMock.ResetCommands()
+		err := server.PlayFavorite(playerId, c.favorite)
+		if (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		commands := squeezeMock.Commands()
+		last := ""
+		if len(commands) > 0 {
+			last = commands[len(commands)-1]
+		}
+		if c.expectedCommand != "" && last != c.expectedCommand {
+			t.Errorf("[%v] bad command: %v, wants %v", c.name, last, c.expectedCommand)
+		}
+		if c.expectedCommand == "" && len(commands) > 0 {
+			t.Errorf("[%v] no command should be sent: %#v", c.name, commands)
+		}
+		if contains(commands, "favorites items 0 500 want_url:1") {
+			t.Errorf("[%v] known favorites shouldn't be read again: %#v", c.name, commands)
+		}
+	}
+}
+
+func TestServer_PlayFavoriteUnknownFavorites(t *testing.T) {
+	squeezeMock := newFavoritesMock(t)
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	if err := server.PlayFavorite(playerId, "fip"); err != nil {
+		t.Errorf("unable to play favorite: %v", err)
+	}
+	if commands := squeezeMock.Commands(); !contains(commands, "favorites items 0 500 want_url:1") {
+		t.Errorf("favorites should be read when unknown: %#v", commands)
+	}
+}
+
+func TestServer_onFavoritesChanged(t *testing.T) {
+	squeezeMock := newFavoritesMock(t)
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("favorites changed\n")
+
+	favorites := <-server.NotifyFavoritesChange()
+	if len(favorites) != 2 {
+		t.Errorf("bad favorites: %#v", favorites)
+	}
+}
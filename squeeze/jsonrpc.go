@@ -0,0 +1,236 @@
+package squeeze
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// jsonRpcLoopFirstKeys are the keys that start an item of a '*_loop' result, in priority order.
+// Cli decoders split items on their first key, json objects don't keep fields order.
+var jsonRpcLoopFirstKeys = []string{"playlist index", "playerindex", "id", "contributor_id", "album_id", "track_id", "genre_id",
+	"sync_members"}
+
+// jsonRpcClient send requests to the '/jsonrpc.js' endpoint of the web server
+// and translate json results to cli fields, so decoders are shared with the cli transport.
+type jsonRpcClient struct {
+	url    string
+	client *http.Client
+	lastId int64
+
+	mu       sync.Mutex
+	username string
+	password string
+}
+
+type jsonRpcRequest struct {
+	Id     int64         `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+type jsonRpcResponse struct {
+	Id     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  interface{}     `json:"error"`
+}
+
+func newJsonRpcClient(baseUrl string) *jsonRpcClient {
+	return &jsonRpcClient{
+		url:    strings.TrimRight(baseUrl, "/") + "/jsonrpc.js",
+		client: &http.Client{Timeout: defaultRequestTimeout},
+	}
+}
+
+func (c *jsonRpcClient) setCredentials(username, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.username, c.password = username, password
+}
+
+func (c *jsonRpcClient) query(requests ...request) ([][]string, error) {
+	values := make([][]string, 0, len(requests))
+	for _, r := range requests {
+		v, err := c.call(r)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (c *jsonRpcClient) call(r request) ([]string, error) {
+	args := make([]interface{}, 0, len(r.args))
+	for _, arg := range r.args {
+		args = append(args, arg)
+	}
+	body, err := json.Marshal(jsonRpcRequest{
+		Id:     atomic.AddInt64(&c.lastId, 1),
+		Method: "slim.request",
+		Params: []interface{}{string(r.player), args},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.mu.Lock()
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	username := c.username
+	c.mu.Unlock()
+
+	log.Debugf("send json-rpc request %s", body)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request to %v: %v", c.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%w for user '%v'", ErrAuthentication, username)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected http status from %v: %v", c.url, resp.Status)
+	}
+
+	var response jsonRpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("unable to decode response: %v", err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("json-rpc error: %v", response.Error)
+	}
+	result, err := decodeJsonResult(response.Result)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode result: %v", err)
+	}
+	return jsonRpcFields(r, result), nil
+}
+
+// decodeJsonResult decode a result object, numbers are kept as written by the server
+func decodeJsonResult(raw json.RawMessage) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	if len(raw) == 0 {
+		return result, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// jsonRpcFields build the cli response of a request: the '?' of a query is replaced by the '_name' result
+// and other results are appended as 'key:value' fields, loop items after the top level fields
+func jsonRpcFields(r request, result map[string]interface{}) []string {
+	fields := make([]string, 0, len(r.args)+len(result)+1)
+	if r.player != "" {
+		fields = append(fields, string(r.player))
+	}
+
+	answer, hasAnswer := "", false
+	keys := make([]string, 0, len(result))
+	loops := make([]string, 0)
+	for k, v := range result {
+		switch {
+		case strings.HasPrefix(k, "_"):
+			answer, hasAnswer = jsonRpcValue(v)
+		case strings.HasSuffix(k, "_loop"):
+			loops = append(loops, k)
+		default:
+			keys = append(keys, k)
+		}
+	}
+	for _, arg := range r.args {
+		if arg == "?" && hasAnswer {
+			arg = answer
+		}
+		fields = append(fields, arg)
+	}
+
+	sort.Strings(keys)
+	fields = appendTags(fields, keys, result)
+
+	sort.Strings(loops)
+	for _, loop := range loops {
+		items, ok := result[loop].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, i := range items {
+			item, ok := i.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fields = appendTags(fields, loopItemKeys(item), item)
+		}
+	}
+	return fields
+}
+
+// loopItemKeys return the keys of a loop item, first key first then sorted
+func loopItemKeys(item map[string]interface{}) []string {
+	first := ""
+	for _, k := range jsonRpcLoopFirstKeys {
+		if _, ok := item[k]; ok {
+			first = k
+			break
+		}
+	}
+	keys := make([]string, 0, len(item))
+	for k := range item {
+		if k != first {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if first != "" {
+		keys = append([]string{first}, keys...)
+	}
+	return keys
+}
+
+func appendTags(fields, keys []string, values map[string]interface{}) []string {
+	for _, k := range keys {
+		if v, ok := jsonRpcValue(values[k]); ok {
+			fields = append(fields, k+":"+v)
+		}
+	}
+	return fields
+}
+
+// jsonRpcValue format a scalar json value as the cli does, nested objects are ignored
+func jsonRpcValue(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	case bool:
+		if value {
+			return "1", true
+		}
+		return "0", true
+	default:
+		return "", false
+	}
+}
+
+func (c *jsonRpcClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,200 @@
+package squeeze
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_jsonRpcFields(t *testing.T) {
+	cases := []struct {
+		name           string
+		request        request
+		result         string
+		expectedFields []string
+	}{
+		{"Query", request{player: "p1", args: []string{"mixer", "volume", "?"}}, `{"_volume":"45"}`,
+			[]string{"p1", "mixer", "volume", "45"}},
+		{"Server query", request{args: []string{"player", "count", "?"}}, `{"_count":2}`,
+			[]string{"player", "count", "2"}},
+		{"Command", request{player: "p1", args: []string{"play"}}, `{}`, []string{"p1", "play"}},
+		{"Tagged", request{player: "p1", args: []string{"status", "-", "1"}},
+			`{"mode":"play","time":23.5,"remoteMeta":{"title":"x"},"power":true,"playlist_loop":[{"title":"Lucille","playlist index":0,"artist":"Little Richard"}]}`,
+			[]string{"p1", "status", "-", "1", "mode:play", "power:1", "time:23.5",
+				"playlist index:0", "artist:Little Richard", "title:Lucille"}},
+		{"Items", request{args: []string{"players", "0", "2"}},
+			`{"count":2,"players_loop":[{"name":"Kitchen","playerindex":"0"},{"playerindex":"1","name":"Bedroom"}]}`,
+			[]string{"players", "0", "2", "count:2", "playerindex:0", "name:Kitchen", "playerindex:1", "name:Bedroom"}},
+		{"Sync groups", request{args: []string{"syncgroups", "?"}},
+			`{"syncgroups_loop":[{"sync_member_names":"Kitchen,Bedroom","sync_members":"p1,p2"}]}`,
+			[]string{"syncgroups", "?", "sync_members:p1,p2", "sync_member_names:Kitchen,Bedroom"}},
+	}
+
+	for _, c := range cases {
+		result, err := decodeJsonResult([]byte(c.result))
+		if err != nil {
+			t.Fatalf("[%v] bad result: %v", c.name, err)
+		}
+		fields := jsonRpcFields(c.request, result)
+		if !reflect.DeepEqual(fields, c.expectedFields) {
+			t.Errorf("[%v] bad fields: %#v, wants %#v", c.name, fields, c.expectedFields)
+		}
+	}
+}
+
+func TestJsonRpcServer(t *testing.T) {
+	lms := newJsonRpcMock("", "")
+	ts := httptest.NewServer(lms)
+	defer ts.Close()
+
+	server := NewJsonRpc(ts.URL + "/")
+	defer server.Close()
+
+	if err := server.RefreshPlayers(); err != nil {
+		t.Fatalf("unable to refresh players: %v", err)
+	}
+	if players := server.Players(); len(players) != 1 || players[0].Name != "Kitchen" || !players[0].Connected {
+		t.Errorf("bad players: %#v", players)
+	}
+
+	track, err := server.CurrentTrack(playerId)
+	if err != nil {
+		t.Fatalf("unable to read track: %v", err)
+	}
+	expectedTrack := Track{Artist: "Tenderlonious", Album: "On flute", Title: "In A Sentimental Mood", Year: 2019,
+		CurrentTime: 23.5, Duration: 241.5}
+	if *track != expectedTrack {
+		t.Errorf("bad track: %#v, wants %#v", *track, expectedTrack)
+	}
+
+	m, err := server.Mixer(playerId)
+	if err != nil {
+		t.Fatalf("unable to read mixer: %v", err)
+	}
+	if m.Volume != 40 || !m.Muted {
+		t.Errorf("bad mixer: %#v", *m)
+	}
+
+	mode, err := server.Mode(playerId)
+	if err != nil || mode != Paused {
+		t.Errorf("bad mode: %v, %v", mode, err)
+	}
+
+	lms.Reset()
+	if err := server.Next(playerId); err != nil {
+		t.Errorf("unable to send command: %v", err)
+	}
+	if commands := lms.Commands(); !reflect.DeepEqual(commands, []string{"playerId playlist index +1"}) {
+		t.Errorf("bad commands: %#v", commands)
+	}
+}
+
+func TestJsonRpcServer_Authentication(t *testing.T) {
+	cases := []struct {
+		name        string
+		username    string
+		password    string
+		expectedErr bool
+	}{
+		{"Valid credentials", "user", "password", false},
+		{"Bad password", "user", "other", true},
+		{"Missing credentials", "", "", true},
+	}
+
+	ts := httptest.NewServer(newJsonRpcMock("user", "password"))
+	defer ts.Close()
+
+	for _, c := range cases {
+		server := NewJsonRpc(ts.URL)
+		server.SetCredentials(c.username, c.password)
+		_, err := server.Mode(playerId)
+		if (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), ErrAuthentication.Error()) {
+			t.Errorf("[%v] authentication failure expected: %v", c.name, err)
+		}
+		_ = server.Close()
+	}
+}
+
+// jsonRpcMock is a minimal '/jsonrpc.js' endpoint for a single player
+type jsonRpcMock struct {
+	username string
+	password string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newJsonRpcMock(username, password string) *jsonRpcMock {
+	return &jsonRpcMock{username: username, password: password}
+}
+
+func (m *jsonRpcMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/jsonrpc.js" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	if m.username != "" {
+		if u, p, ok := r.BasicAuth(); !ok || u != m.username || p != m.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var req struct {
+		Id     int64             `json:"id"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Params) != 2 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var player string
+	var args []string
+	if json.Unmarshal(req.Params[0], &player) != nil || json.Unmarshal(req.Params[1], &args) != nil {
+		http.Error(w, "bad params", http.StatusBadRequest)
+		return
+	}
+	cmd := strings.Join(append([]string{player}, args...), " ")
+	m.mu.Lock()
+	m.commands = append(m.commands, strings.TrimSpace(cmd))
+	m.mu.Unlock()
+
+	result := "{}"
+	switch strings.TrimSpace(cmd) {
+	case "player count ?":
+		result = `{"_count":1}`
+	case "players 0 1":
+		result = `{"count":1,"players_loop":[{"playerindex":"0","playerid":"playerId","name":"Kitchen","connected":1}]}`
+	case "playerId status - 1 tags:" + statusTags:
+		result = `{"player_name":"Kitchen","mode":"play","time":23.5,"current_title":"FIP","remoteMeta":{"title":"x"},` +
+			`"playlist_loop":[{"playlist index":0,"title":"In A Sentimental Mood","artist":"Tenderlonious",` +
+			`"album":"On flute / 2019","duration":241.5}]}`
+	case "playerId mixer volume ?":
+		result = `{"_volume":"-40"}`
+	case "playerId mixer muting ?":
+		result = `{"_muting":1}`
+	case "playerId mode ?":
+		result = `{"_mode":"pause"}`
+	}
+	_, _ = fmt.Fprintf(w, `{"id":%d,"method":"slim.request","params":[],"result":%v}`, req.Id, result)
+}
+
+func (m *jsonRpcMock) Commands() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.commands...)
+}
+
+func (m *jsonRpcMock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands = nil
+}
@@ -0,0 +1,119 @@
+package squeeze
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLibraryItems limit the page size of library queries
+	maxLibraryItems = 500
+	// libraryTimeout is the timeout of library queries, large pages of a big library are slow to build
+	libraryTimeout = 30 * time.Second
+)
+
+// Library commands
+const (
+	LibraryArtists     = "artists"
+	LibraryAlbums      = "albums"
+	LibraryTitles      = "titles"
+	LibraryGenres      = "genres"
+	LibrarySearch      = "search"
+	LibraryMusicFolder = "musicfolder"
+)
+
+// libraryFirstKeys are the keys starting an item in the response of each command,
+// search results mix contributors, albums, tracks and genres
+var libraryFirstKeys = map[string][]string{
+	LibraryArtists:     {"id"},
+	LibraryAlbums:      {"id"},
+	LibraryTitles:      {"id"},
+	LibraryGenres:      {"id"},
+	LibraryMusicFolder: {"id"},
+	LibrarySearch:      {"contributor_id", "album_id", "track_id", "genre_id"},
+}
+
+// LibraryQuery is a page of a library command, Params are sent as 'key:value' parameters,
+// as 'artist_id:12' or 'tags:al'
+type LibraryQuery struct {
+	Command string
+	Start   int
+	Count   int
+	Search  string
+	Params  map[string]string
+}
+
+// LibraryResult hold the raw tagged fields of the items, Count is the total number of items
+type LibraryResult struct {
+	Count int
+	Items []map[string]string
+}
+
+func (s *Server) Library(q LibraryQuery) (*LibraryResult, error) {
+	firstKeys, ok := libraryFirstKeys[q.Command]
+	if !ok {
+		return nil, fmt.Errorf("unknown library command '%v'", q.Command)
+	}
+	if q.Start < 0 || q.Count < 0 || q.Count > maxLibraryItems {
+		return nil, fmt.Errorf("invalid page %d+%d, count must be between 0 and %d", q.Start, q.Count, maxLibraryItems)
+	}
+
+	args := []string{q.Command, strconv.Itoa(q.Start), strconv.Itoa(q.Count)}
+	if q.Search != "" {
+		if q.Command == LibrarySearch {
+			args = append(args, "term:"+q.Search)
+		} else {
+			args = append(args, "search:"+q.Search)
+		}
+	}
+	keys := make([]string, 0, len(q.Params))
+	for k := range q.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, k+":"+q.Params[k])
+	}
+
+	responses, err := s.library.query(request{args: args})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query library: %v", err)
+	}
+	values := responses[0]
+	head, items := parseLibraryItems(values, firstKeys)
+	return &LibraryResult{Count: parseInt(head["count"]), Items: items}, nil
+}
+
+// parseLibraryItems split the tagged fields on any of the first keys, the item type is the first key
+// without its '_id' suffix when there are many kinds of items. Counts are returned with the head fields.
+func parseLibraryItems(values []string, firstKeys []string) (map[string]string, []map[string]string) {
+	head := make(map[string]string)
+	items := make([]map[string]string, 0)
+	current := head
+	for _, field := range values {
+		key, value, ok := splitTag(field)
+		if !ok {
+			continue
+		}
+		// Counts are sent after or between items
+		if key == "count" || strings.HasSuffix(key, "_count") {
+			head[key] = value
+			continue
+		}
+		for _, k := range firstKeys {
+			if key != k {
+				continue
+			}
+			current = make(map[string]string)
+			if len(firstKeys) > 1 {
+				current["type"] = strings.TrimSuffix(k, "_id")
+			}
+			items = append(items, current)
+		}
+		current[key] = value
+	}
+	return head, items
+}
@@ -0,0 +1,120 @@
+package squeeze
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServer_Library(t *testing.T) {
+	cases := []struct {
+		name          string
+		query         LibraryQuery
+		request       string
+		response      string
+		expectedCount int
+		expectedItems []map[string]string
+	}{
+		{"Artists",
+			LibraryQuery{Command: LibraryArtists, Start: 0, Count: 2, Search: "little"},
+			"artists 0 2 search:little",
+			"artists 0 2 search%3Alittle id%3A12 artist%3ALittle%20Richard id%3A13 artist%3ALittle%20Dragon count%3A5",
+			5,
+			[]map[string]string{{"id": "12", "artist": "Little Richard"}, {"id": "13", "artist": "Little Dragon"}}},
+		{"Albums of artist",
+			LibraryQuery{Command: LibraryAlbums, Start: 10, Count: 1, Params: map[string]string{"artist_id": "12", "tags": "ly"}},
+			"albums 10 1 artist_id:12 tags:ly",
+			"albums 10 1 artist_id%3A12 tags%3Aly id%3A7 album%3AHere's%20Little%20Richard year%3A1957 count%3A11",
+			11,
+			[]map[string]string{{"id": "7", "album": "Here's Little Richard", "year": "1957"}}},
+		{"Search",
+			LibraryQuery{Command: LibrarySearch, Count: 10, Search: "lucille"},
+			"search 0 10 term:lucille",
+			"search 0 10 term%3Alucille count%3A2 contributors_count%3A1 contributor_id%3A12 contributor%3ALittle%20Richard " +
+				"tracks_count%3A1 track_id%3A99 track%3ALucille",
+			2,
+			[]map[string]string{
+				{"type": "contributor", "contributor_id": "12", "contributor": "Little Richard"},
+				{"type": "track", "track_id": "99", "track": "Lucille"},
+			}},
+		{"Empty",
+			LibraryQuery{Command: LibraryMusicFolder, Count: 10, Params: map[string]string{"folder_id": "3"}},
+			"musicfolder 0 10 folder_id:3",
+			"musicfolder 0 10 folder_id%3A3 count%3A0",
+			0,
+			[]map[string]string{}},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	for _, c := range cases {
+		squeezeMock.SetResponse(c.request, c.response)
+		result, err := server.Library(c.query)
+		if err != nil {
+			t.Errorf("[%v] unable to query library: %v", c.name, err)
+			continue
+		}
+		if result.Count != c.expectedCount {
+			t.Errorf("[%v] bad count: %v, wants %v", c.name, result.Count, c.expectedCount)
+		}
+		if !reflect.DeepEqual(result.Items, c.expectedItems) {
+			t.Errorf("[%v] bad items: %#v, wants %#v", c.name, result.Items, c.expectedItems)
+		}
+	}
+}
+
+func TestServer_LibraryInvalidQuery(t *testing.T) {
+	cases := []struct {
+		name  string
+		query LibraryQuery
+	}{
+		{"Unknown command", LibraryQuery{Command: "players", Count: 10}},
+		{"Negative start", LibraryQuery{Command: LibraryArtists, Start: -1, Count: 10}},
+		{"Too many items", LibraryQuery{Command: LibraryArtists, Count: maxLibraryItems + 1}},
+	}
+
+	server := New("127.0.0.1:0")
+	for _, c := range cases {
+		if _, err := server.Library(c.query); err == nil {
+			t.Errorf("[%v] query should be rejected", c.name)
+		}
+	}
+}
+
+func TestServer_LibraryTimeout(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("albums 0 500", "")
+	squeezeMock.SetRawMode("play")
+
+	server := New(squeezeMock.Addr())
+	server.library.(*cliClient).timeout = 50 * time.Millisecond
+	defer server.Close()
+
+	// A pending player request is sent before the library query times out
+	if _, err := server.Mode(playerId); err != nil {
+		t.Fatalf("unable to read mode: %v", err)
+	}
+	chanMode := make(chan error)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, err := server.Mode(playerId)
+		chanMode <- err
+	}()
+	if _, err := server.Library(LibraryQuery{Command: LibraryAlbums, Count: maxLibraryItems}); err == nil {
+		t.Errorf("library query should time out")
+	}
+	if err := <-chanMode; err != nil {
+		t.Errorf("player request shouldn't fail with the library query: %v", err)
+	}
+}
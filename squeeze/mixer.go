@@ -0,0 +1,82 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+)
+
+type Mixer struct {
+	Player PlayerId
+	Volume int
+	Muted  bool
+}
+
+func (s *Server) SetVolume(id PlayerId, volume int) error {
+	if volume < 0 || volume > 100 {
+		return fmt.Errorf("invalid volume %v, must be between 0 and 100", volume)
+	}
+	return s.Command(id, "mixer", "volume", strconv.Itoa(volume))
+}
+
+func (s *Server) StepVolume(id PlayerId, delta int) error {
+	return s.Command(id, "mixer", "volume", fmt.Sprintf("%+d", delta))
+}
+
+func (s *Server) Mute(id PlayerId) error {
+	return s.Command(id, "mixer", "muting", "1")
+}
+
+func (s *Server) Unmute(id PlayerId) error {
+	return s.Command(id, "mixer", "muting", "0")
+}
+
+func (s *Server) Mixer(id PlayerId) (*Mixer, error) {
+	responses, err := s.queryAll(id, []string{"mixer", "volume", "?"}, []string{"mixer", "muting", "?"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch mixer state: %v", err)
+	}
+
+	values := responses[0]
+	if len(values) < 4 {
+		return nil, fmt.Errorf("no volume value in response %v", values)
+	}
+	volume, err := strconv.ParseFloat(values[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse volume value \"%v\": %v", values[3], err)
+	}
+
+	values = responses[1]
+	return newMixer(id, volume, len(values) >= 4 && values[3] == "1"), nil
+}
+
+// newMixer build the mixer state from the raw volume of the server and its muting flag
+func newMixer(id PlayerId, volume float64, muted bool) *Mixer {
+	// When player is muted, lms reports the volume to restore as a negative value
+	if volume < 0 {
+		muted = true
+		volume = -volume
+	}
+	return &Mixer{Player: id, Volume: int(volume), Muted: muted}
+}
+
+func (s *Server) NotifyMixerChange() <-chan *Mixer {
+	return s.chanMixer
+}
+
+func (s *Server) onMixer(line string) {
+	id := parsePlayerId(line)
+	m, err := s.Mixer(id)
+	if err != nil {
+		log.Errorf("unable to read mixer state for player %v: %v", id, err)
+		return
+	}
+	s.notifyMixer(m)
+}
+
+func (s *Server) notifyMixer(m *Mixer) {
+	select {
+	case s.chanMixer <- m:
+	case <-s.done:
+	}
+}
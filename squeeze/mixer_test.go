@@ -0,0 +1,102 @@
+package squeeze
+
+import (
+	"testing"
+)
+
+func TestServer_Mixer(t *testing.T) {
+	cases := []struct {
+		name          string
+		rawVolume     string
+		rawMuting     string
+		expectedMixer Mixer
+	}{
+		{"Simple", "45", "0", Mixer{Player: playerId, Volume: 45, Muted: false}},
+		{"Muted", "45", "1", Mixer{Player: playerId, Volume: 45, Muted: true}},
+		{"Muted with negative volume", "-30", "0", Mixer{Player: playerId, Volume: 30, Muted: true}},
+		{"Float volume", "12.5", "0", Mixer{Player: playerId, Volume: 12, Muted: false}},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	for _, c := range cases {
+		squeezeMock.SetRawMixer(c.rawVolume, c.rawMuting)
+
+		mixer, err := server.Mixer(playerId)
+		if err != nil {
+			t.Errorf("[%v] unable to read mixer: %v", c.name, err)
+			continue
+		}
+		if *mixer != c.expectedMixer {
+			t.Errorf("[%v] bad mixer: %#v, wants %#v", c.name, *mixer, c.expectedMixer)
+		}
+	}
+}
+
+func TestServer_MixerCommand(t *testing.T) {
+	cases := []struct {
+		name            string
+		command         func(s *Server) error
+		expectedCommand string
+		expectedErr     bool
+	}{
+		{"Set volume", func(s *Server) error { return s.SetVolume(playerId, 40) }, "playerId mixer volume 40", false},
+		{"Invalid volume", func(s *Server) error { return s.SetVolume(playerId, 140) }, "", true},
+		{"Volume up", func(s *Server) error { return s.StepVolume(playerId, 5) }, "playerId mixer volume +5", false},
+		{"Volume down", func(s *Server) error { return s.StepVolume(playerId, -5) }, "playerId mixer volume -5", false},
+		{"Mute", func(s *Server) error { return s.Mute(playerId) }, "playerId mixer muting 1", false},
+		{"Unmute", func(s *Server) error { return s.Unmute(playerId) }, "playerId mixer muting 0", false},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	for _, c := range cases {
+		squeezeMock.ResetCommands()
+
+		err := c.command(server)
+		if (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		commands := squeezeMock.Commands()
+		if c.expectedErr {
+			if len(commands) != 0 {
+				t.Errorf("[%v] no command expected: %#v", c.name, commands)
+			}
+			continue
+		}
+		if len(commands) != 1 || commands[0] != c.expectedCommand {
+			t.Errorf("[%v] bad commands sent: %#v, wants %#v", c.name, commands, []string{c.expectedCommand})
+		}
+	}
+}
+
+func TestServer_processMixerEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetRawMixer("-20", "1")
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("playerId mixer volume +5\n")
+
+	mixer := <-server.NotifyMixerChange()
+	expected := Mixer{Player: playerId, Volume: 20, Muted: true}
+	if *mixer != expected {
+		t.Errorf("bad mixer: %#v, wants %#v", *mixer, expected)
+	}
+}
@@ -1,11 +1,7 @@
 package squeeze
 
 import (
-	"bufio"
-	"fmt"
 	log "github.com/sirupsen/logrus"
-	"io"
-	"net/url"
 	"strconv"
 	"strings"
 )
@@ -20,28 +16,24 @@ type MetadataParser interface {
 	TimeParser
 }
 
-type CurrentTitleParser interface {
-	CurrentTitle(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error)
-}
-
 type ArtistParser interface {
-	Artist(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error)
+	Artist(status *Status) string
 }
 
 type AlbumParser interface {
-	Album(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error)
+	Album(status *Status) string
 }
 
 type TitleParser interface {
-	Title(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error)
+	Title(status *Status) string
 }
 
 type YearParser interface {
-	Year(writer io.Writer, reader *bufio.Reader, id PlayerId) (int, error)
+	Year(status *Status) int
 }
 
 type GenreParser interface {
-	Genre(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error)
+	Genre(status *Status) string
 }
 
 type TrackDuration float64
@@ -49,7 +41,7 @@ type TrackDuration float64
 var NilTrackDuration = TrackDuration(-1)
 
 type DurationParser interface {
-	Duration(writer io.Writer, reader *bufio.Reader, id PlayerId) (TrackDuration, error)
+	Duration(status *Status) TrackDuration
 }
 
 type TrackTime float64
@@ -57,7 +49,27 @@ type TrackTime float64
 var NilTrackTime = TrackTime(-1)
 
 type TimeParser interface {
-	Time(writer io.Writer, reader *bufio.Reader, id PlayerId) (TrackTime, error)
+	Time(status *Status) TrackTime
+}
+
+// ParseTrack build the track metadata from the player status
+func ParseTrack(mp MetadataParser, status *Status) *Track {
+	t := Track{
+		Artist: mp.Artist(status),
+		Album:  mp.Album(status),
+		Title:  mp.Title(status),
+		Genre:  mp.Genre(status),
+	}
+	if year := mp.Year(status); year > 0 {
+		t.Year = year
+	}
+	if d := mp.Duration(status); d != NilTrackDuration {
+		t.Duration = float64(d)
+	}
+	if tm := mp.Time(status); tm != NilTrackTime {
+		t.CurrentTime = float64(tm)
+	}
+	return &t
 }
 
 type DefaultParser struct {
@@ -72,209 +84,47 @@ type DefaultParser struct {
 
 type DefaultArtistParser struct{}
 
-func (p DefaultArtistParser) Artist(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error) {
-	_, err := fmt.Fprintf(writer, "%s artist ?\r\n", id)
-	if err != nil {
-		return "", fmt.Errorf("unable to fetch track artist: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("unable to read response: %v", err)
-	}
-
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	rawValues := strings.Split(line, " ")
-	if len(rawValues) < 3 {
-		log.Debugf("no artist metadata for current track")
-		return "", nil
-	}
-	rawValue := rawValues[2]
-	artist, err := url.QueryUnescape(rawValue)
-	if err != nil {
-		return "", fmt.Errorf("unable to unescape artist \"%v\": %v", rawValue, err)
-	}
-
-	return artist, nil
+func (p DefaultArtistParser) Artist(status *Status) string {
+	return status.Artist
 }
 
 type DefaultAlbumParser struct{}
 
-func (p DefaultAlbumParser) Album(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error) {
-	_, err := fmt.Fprintf(writer, "%s album ?\r\n", id)
-	if err != nil {
-		return "", fmt.Errorf("unable to fetch track album: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("unable to read response: %v", err)
-	}
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	log.Debugf("parse raw album value '%v'", line)
-	rawValues := strings.Split(line, " ")
-	if len(rawValues) < 3 {
-		log.Debugf("no album metadata for current track")
-		return "", nil
-	}
-	rawValue := rawValues[2]
-	album, err := url.QueryUnescape(rawValue)
-	if err != nil {
-		return "", fmt.Errorf("unable to unescape album \"%v\": %v", rawValue, err)
-	}
-	log.Debugf("find album '%v'", album)
-	return album, nil
+func (p DefaultAlbumParser) Album(status *Status) string {
+	return status.Album
 }
 
 type DefaultYearParser struct{}
 
-func (p DefaultYearParser) Year(writer io.Writer, reader *bufio.Reader, id PlayerId) (int, error) {
-	_, err := fmt.Fprintf(writer, "%s year ?\r\n", id)
-	if err != nil {
-		return 0, fmt.Errorf("unable to fetch track year: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return 0, fmt.Errorf("unable to read response: %v", err)
-	}
-
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	rawValue := strings.Split(line, " ")[2]
-	rawYear, err := url.QueryUnescape(rawValue)
-	if err != nil {
-		return 0, fmt.Errorf("unable to unescape year \"%v\": %v", rawValue, err)
-	}
-
-	year, err := strconv.Atoi(strings.Trim(rawYear, "\r"))
-	if err != nil {
-		log.WithFields(log.Fields{"parser": "year", "rawYear": rawYear}).Debug("year field isn't an integer, value ignored")
-		year = 0
-	}
-	return year, nil
-}
-
-type DefaultCurrentTitleParser struct{}
-
-func (p DefaultCurrentTitleParser) CurrentTitle(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error) {
-	_, err := fmt.Fprintf(writer, "%s current_title ?\r\n", id)
-	if err != nil {
-		return "", fmt.Errorf("unable to fetch track title: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("unable to read response: %v", err)
-	}
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	rawValue := strings.Split(line, " ")[2]
-	title, err := url.QueryUnescape(rawValue)
-	if err != nil {
-		return "", fmt.Errorf("unable to unescape title \"%v\": %v", rawValue, err)
-	}
-	return title, nil
+func (p DefaultYearParser) Year(status *Status) int {
+	return status.Year
 }
 
 type DefaultTitleParser struct{}
 
-func (p DefaultTitleParser) Title(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error) {
-	_, err := fmt.Fprintf(writer, "%s title ?\r\n", id)
-	if err != nil {
-		return "", fmt.Errorf("unable to fetch track title: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("unable to read response: %v", err)
-	}
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	rawValue := strings.Split(line, " ")[2]
-	title, err := url.QueryUnescape(rawValue)
-	if err != nil {
-		return "", fmt.Errorf("unable to unescape title \"%v\": %v", rawValue, err)
-	}
-	return title, nil
+func (p DefaultTitleParser) Title(status *Status) string {
+	return status.Title
 }
 
 type DefaultGenreParser struct{}
 
-func (p DefaultGenreParser) Genre(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error) {
-	_, err := fmt.Fprintf(writer, "%s genre ?\r\n", id)
-	if err != nil {
-		return "", fmt.Errorf("unable to fetch track genre: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("unable to read response: %v", err)
-	}
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	rawValues := strings.Split(line, " ")
-	if len(rawValues) < 3 {
-		log.Debugf("no genre metadata for current track")
-		return "", nil
-	}
-	rawValue := rawValues[2]
-	genre, err := url.QueryUnescape(rawValue)
-	if err != nil {
-		return "", fmt.Errorf("unable to unescape genre \"%v\": %v", rawValue, err)
-	}
-	return genre, nil
+func (p DefaultGenreParser) Genre(status *Status) string {
+	return status.Genre
 }
 
 type DefaultDurationParser struct{}
 
-func (p DefaultDurationParser) Duration(writer io.Writer, reader *bufio.Reader, id PlayerId) (TrackDuration, error) {
-	_, err := fmt.Fprintf(writer, "%s duration ?\r\n", id)
-	if err != nil {
-		return NilTrackDuration, fmt.Errorf("unable to fetch track duration: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return NilTrackDuration, fmt.Errorf("unable to read response: %v", err)
-	}
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	rawValue := strings.Split(line, " ")[2]
-
-	d, err := strconv.ParseFloat(strings.Trim(rawValue, "\r"), 64)
-	if err != nil {
-		return NilTrackDuration, fmt.Errorf("unable to parse duration value \"%v\": %v", rawLine, err)
-	}
-	return TrackDuration(d), nil
+func (p DefaultDurationParser) Duration(status *Status) TrackDuration {
+	return status.Duration
 }
 
 type DefaultTimeParser struct{}
 
-func (p DefaultTimeParser) Time(writer io.Writer, reader *bufio.Reader, id PlayerId) (TrackTime, error) {
-	_, err := fmt.Fprintf(writer, "%s time ?\r\n", id)
-	if err != nil {
-		return NilTrackTime, fmt.Errorf("unable to fetch track current time: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return NilTrackTime, fmt.Errorf("unable to read response: %v", err)
-	}
-
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	rawValue := strings.Split(line, " ")[2]
-
-	tm, err := strconv.ParseFloat(strings.Trim(rawValue, "\r"), 64)
-	if err != nil {
-		return NilTrackTime, fmt.Errorf("unable to parse time value \"%v\": %v", rawLine, err)
-	}
-	return TrackTime(tm), nil
+func (p DefaultTimeParser) Time(status *Status) TrackTime {
+	return status.Time
 }
 
+// RadioFranceParser read the year at the end of the album field, as 'Album / 1973'
 type RadioFranceParser struct {
 	yearParser DefaultYearParser
 	DefaultTitleParser
@@ -284,12 +134,8 @@ type RadioFranceParser struct {
 	DefaultTimeParser
 }
 
-func (r RadioFranceParser) Year(writer io.Writer, reader *bufio.Reader, id PlayerId) (int, error) {
-	line, err := r.readAlbumMetadata(writer, reader, id)
-	if err != nil {
-		return 0, fmt.Errorf("unable to parse result to read album: %v", err)
-	}
-
+func (r RadioFranceParser) Year(status *Status) int {
+	line := status.Album
 	log.Debugf("search year in album metadata '%v'", line)
 	fields := strings.Split(line, "/")
 	if len(fields) >= 2 {
@@ -298,20 +144,16 @@ func (r RadioFranceParser) Year(writer io.Writer, reader *bufio.Reader, id Playe
 			log.Warnf("unable to parse year in album value \"%v\": %v", line, err)
 		} else {
 			log.Debugf("find year value '%v'", year)
-			return year, nil
+			return year
 		}
 	}
 	log.Debug("no year found in album line, search it in default fields")
-	return r.yearParser.Year(writer, reader, id)
+	return r.yearParser.Year(status)
 }
 
-func (r RadioFranceParser) Album(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error) {
-
+func (r RadioFranceParser) Album(status *Status) string {
 	var album string
-	line, err := r.readAlbumMetadata(writer, reader, id)
-	if err != nil {
-		return "", fmt.Errorf("unable to parse result to read album: %v", err)
-	}
+	line := status.Album
 
 	log.Debugf("check if year exists in album metadata '%v'", line)
 	fields := strings.Split(line, "/")
@@ -322,31 +164,5 @@ func (r RadioFranceParser) Album(writer io.Writer, reader *bufio.Reader, id Play
 	}
 
 	log.Debugf("find album '%v'", album)
-	return album, nil
-}
-
-func (r RadioFranceParser) readAlbumMetadata(writer io.Writer, reader *bufio.Reader, id PlayerId) (string, error) {
-	_, err := fmt.Fprintf(writer, "%s album ?\r\n", id)
-	if err != nil {
-		return "", fmt.Errorf("unable to fetch track escapedLine: %v", err)
-	}
-
-	rawLine, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("unable to read response: %v", err)
-	}
-	line := strings.ReplaceAll(rawLine, "\n", "")
-	line = strings.ReplaceAll(line, "\r", "")
-	log.Debugf("parse raw escapedLine value '%v'", line)
-	rawValues := strings.Split(line, " ")
-	if len(rawValues) < 3 {
-		log.Debugf("no escapedLine metadata for current track")
-		return "", nil
-	}
-	rawValue := rawValues[2]
-	escapedLine, err := url.QueryUnescape(rawValue)
-	if err != nil {
-		return "", fmt.Errorf("unable to unescape escapedLine \"%v\": %v", rawValue, err)
-	}
-	return escapedLine, nil
+	return album
 }
@@ -0,0 +1,114 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+type PlaybackState string
+
+const (
+	Playing      = PlaybackState("playing")
+	Paused       = PlaybackState("paused")
+	Stopped      = PlaybackState("stopped")
+	UnknownState = PlaybackState("unknown")
+)
+
+type Playback struct {
+	Player PlayerId
+	State  PlaybackState
+}
+
+// playbackState convert the lms mode ('play', 'pause' or 'stop')
+func playbackState(mode string) PlaybackState {
+	switch mode {
+	case "play":
+		return Playing
+	case "pause":
+		return Paused
+	case "stop":
+		return Stopped
+	default:
+		return UnknownState
+	}
+}
+
+// Mode query the current playback state of the player
+func (s *Server) Mode(id PlayerId) (PlaybackState, error) {
+	values, err := s.query(id, "mode", "?")
+	if err != nil {
+		return UnknownState, fmt.Errorf("unable to fetch mode: %v", err)
+	}
+	if len(values) < 3 {
+		return UnknownState, fmt.Errorf("no mode value in response %v", values)
+	}
+	return playbackState(values[2]), nil
+}
+
+// PlaybackState return the last known playback state of the player
+func (s *Server) PlaybackState(id PlayerId) PlaybackState {
+	s.muPlayers.Lock()
+	defer s.muPlayers.Unlock()
+	state, ok := s.playback[id]
+	if !ok {
+		return UnknownState
+	}
+	return state
+}
+
+func (s *Server) NotifyPlaybackChange() <-chan *Playback {
+	return s.chanPlayback
+}
+
+// setPlaybackState register the player state and notify it when changed
+func (s *Server) setPlaybackState(id PlayerId, state PlaybackState) {
+	s.muPlayers.Lock()
+	previous, ok := s.playback[id]
+	s.playback[id] = state
+	s.muPlayers.Unlock()
+
+	if ok && previous == state {
+		return
+	}
+	select {
+	case s.chanPlayback <- &Playback{Player: id, State: state}:
+	case <-s.done:
+	}
+}
+
+// refreshPlaybackState read the player mode after a playback event
+func (s *Server) refreshPlaybackState(id PlayerId) {
+	state, err := s.Mode(id)
+	if err != nil {
+		log.Errorf("unable to read playback state of player %v: %v", id, err)
+		return
+	}
+	s.setPlaybackState(id, state)
+}
+
+// refreshPlayback read the player status after a playback event, the track is notified again with its new state
+func (s *Server) refreshPlayback(id PlayerId) {
+	before := s.PlaybackState(id)
+	t, err := s.playerTrack(id)
+	if err != nil {
+		log.Errorf("unable to read playback state of player %v: %v", id, err)
+		return
+	}
+	if t.State != before {
+		s.notifyTrack(t)
+	}
+}
+
+// isPlaybackEvent return true for 'play', 'pause', 'stop', 'mode', 'playlist pause' and 'playlist stop' events
+func isPlaybackEvent(fields []string) bool {
+	if len(fields) < 2 {
+		return false
+	}
+	switch fields[1] {
+	case "play", "pause", "stop", "mode":
+		return true
+	case "playlist":
+		return len(fields) > 2 && (fields[2] == "pause" || fields[2] == "stop")
+	}
+	return false
+}
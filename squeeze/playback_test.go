@@ -0,0 +1,156 @@
+package squeeze
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServer_Mode(t *testing.T) {
+	cases := []struct {
+		name          string
+		rawMode       string
+		expectedState PlaybackState
+	}{
+		{"Playing", "play", Playing},
+		{"Paused", "pause", Paused},
+		{"Stopped", "stop", Stopped},
+		{"Unknown", "", UnknownState},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	for _, c := range cases {
+		squeezeMock.SetRawMode(c.rawMode)
+		state, err := server.Mode(playerId)
+		if err != nil {
+			t.Errorf("[%v] unable to read mode: %v", c.name, err)
+		}
+		if state != c.expectedState {
+			t.Errorf("[%v] bad state: %#v, wants %#v", c.name, state, c.expectedState)
+		}
+	}
+}
+
+func TestServer_processPlaybackEvent(t *testing.T) {
+	cases := []struct {
+		name          string
+		event         string
+		rawMode       string
+		expectedState PlaybackState
+	}{
+		{"Play", "playerId play\n", "play", Playing},
+		{"Playlist pause", "playerId playlist pause 1\n", "pause", Paused},
+		{"Pause toggle", "playerId pause\n", "play", Playing},
+		{"Playlist stop", "playerId playlist stop\n", "stop", Stopped},
+		{"Mode", "playerId mode pause\n", "pause", Paused},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	for _, c := range cases {
+		squeezeMock.SetRawMode(c.rawMode)
+		go server.processEventLine(c.event)
+
+		playback := <-server.NotifyPlaybackChange()
+		if playback.Player != playerId || playback.State != c.expectedState {
+			t.Errorf("[%v] bad playback: %#v, wants %#v", c.name, *playback, Playback{playerId, c.expectedState})
+		}
+		if state := server.PlaybackState(playerId); state != c.expectedState {
+			t.Errorf("[%v] bad registered state: %#v, wants %#v", c.name, state, c.expectedState)
+		}
+		// The track payload holds the new state
+		if track := <-server.NotifyTrackChange(); track.State != c.expectedState {
+			t.Errorf("[%v] bad track state: %#v, wants %#v", c.name, track.State, c.expectedState)
+		}
+	}
+}
+
+func TestServer_processPauseEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetRawMode("play")
+	squeezeMock.SetRawTrack(RawTrack{rawTitle: "Lucille"})
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("playerId playlist newsong Lucille 3\n")
+	<-server.NotifyPlaybackChange()
+	if track := <-server.NotifyTrackChange(); track.State != Playing {
+		t.Errorf("bad track state: %#v, wants %#v", track.State, Playing)
+	}
+
+	squeezeMock.SetRawMode("pause")
+	go server.processEventLine("playerId pause 1\n")
+	<-server.NotifyPlaybackChange()
+	track := <-server.NotifyTrackChange()
+	if track.State != Paused || track.Title != "Lucille" {
+		t.Errorf("paused track should be notified: %#v", *track)
+	}
+
+	// An event which doesn't change the state doesn't notify the track again
+	squeezeMock.ResetCommands()
+	server.processEventLine("playerId mode pause\n")
+	if commands := squeezeMock.Commands(); len(commands) != 1 || !strings.HasPrefix(commands[0], "playerId status") {
+		t.Errorf("a single status query is expected: %#v", commands)
+	}
+}
+
+func TestServer_trackPlaybackState(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetRawMode("play")
+	squeezeMock.SetRawTrack(RawTrack{rawTitle: "Lucille"})
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("playerId playlist newsong Lucille 3\n")
+
+	playback := <-server.NotifyPlaybackChange()
+	if playback.State != Playing {
+		t.Errorf("bad playback state: %#v, wants %#v", playback.State, Playing)
+	}
+	track := <-server.NotifyTrackChange()
+	if track.State != Playing || track.Title != "Lucille" {
+		t.Errorf("bad track: %#v", *track)
+	}
+}
+
+func Test_isPlaybackEvent(t *testing.T) {
+	cases := []struct {
+		event    string
+		expected bool
+	}{
+		{"playerId play", true},
+		{"playerId pause 1", true},
+		{"playerId stop", true},
+		{"playerId mode play", true},
+		{"playerId playlist pause 0", true},
+		{"playerId playlist stop", true},
+		{"playerId playlist newsong", false},
+		{"playerId mixer volume 10", false},
+		{"listen", false},
+	}
+	for _, c := range cases {
+		if isPlaybackEvent(strings.Fields(c.event)) != c.expected {
+			t.Errorf("[%v] bad result, wants %v", c.event, c.expected)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sort"
+	"strconv"
+)
+
+type Player struct {
+	Id        PlayerId
+	Name      string
+	Model     string
+	ModelName string
+	IP        string
+	Firmware  string
+	Connected bool
+}
+
+// Players return a snapshot of the known players sorted by id
+func (s *Server) Players() []Player {
+	s.muPlayers.Lock()
+	defer s.muPlayers.Unlock()
+
+	players := make([]Player, 0, len(s.players))
+	for _, p := range s.players {
+		players = append(players, *p)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Id < players[j].Id })
+	return players
+}
+
+func (s *Server) Player(id PlayerId) (Player, bool) {
+	s.muPlayers.Lock()
+	defer s.muPlayers.Unlock()
+	p, ok := s.players[id]
+	if !ok {
+		return Player{}, false
+	}
+	return *p, true
+}
+
+// RefreshPlayers reload the players registry from the server
+func (s *Server) RefreshPlayers() error {
+	values, err := s.query("", "player", "count", "?")
+	if err != nil {
+		return fmt.Errorf("unable to count players: %v", err)
+	}
+	if len(values) < 3 {
+		return fmt.Errorf("no player count in response %v", values)
+	}
+	count, err := strconv.Atoi(values[2])
+	if err != nil {
+		return fmt.Errorf("unable to parse player count \"%v\": %v", values[2], err)
+	}
+
+	values, err = s.query("", "players", "0", strconv.Itoa(count))
+	if err != nil {
+		return fmt.Errorf("unable to list players: %v", err)
+	}
+
+	s.setPlayers(parseTaggedItems(values, "playerindex"))
+	return nil
+}
+
+// setPlayers replace the players registry with the players items of a 'players' or 'serverstatus' response
+func (s *Server) setPlayers(items []map[string]string) {
+	players := make(map[PlayerId]*Player)
+	for _, item := range items {
+		p := parsePlayer(item)
+		if p.Id == "" {
+			log.Warnf("ignore player without id: %v", item)
+			continue
+		}
+		players[p.Id] = p
+	}
+	log.Debugf("find %d players", len(players))
+
+	s.muPlayers.Lock()
+	s.players = players
+	s.muPlayers.Unlock()
+}
+
+func parsePlayer(item map[string]string) *Player {
+	ip := item["ip"]
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return &Player{
+		Id:        PlayerId(item["playerid"]),
+		Name:      item["name"],
+		Model:     item["model"],
+		ModelName: item["modelname"],
+		IP:        ip,
+		Firmware:  item["firmware"],
+		Connected: item["connected"] == "1",
+	}
+}
+
+func (s *Server) NotifyPlayersChange() <-chan []Player {
+	return s.chanPlayers
+}
+
+func (s *Server) onClient(line string, action string) {
+	id := parsePlayerId(line)
+	switch action {
+	case "new", "reconnect":
+		if err := s.RefreshPlayers(); err != nil {
+			log.Errorf("unable to refresh players after '%v' event of %v: %v", action, id, err)
+			return
+		}
+	case "disconnect":
+		s.muPlayers.Lock()
+		if p, ok := s.players[id]; ok {
+			p.Connected = false
+		}
+		s.muPlayers.Unlock()
+	case "forget":
+		s.muPlayers.Lock()
+		delete(s.players, id)
+		s.muPlayers.Unlock()
+	default:
+		return
+	}
+	select {
+	case s.chanPlayers <- s.Players():
+	case <-s.done:
+	}
+}
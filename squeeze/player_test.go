@@ -0,0 +1,77 @@
+package squeeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+const rawPlayers = "players 0 2 count%3A2 " +
+	"playerindex%3A0 playerid%3A00%3A04%3A20%3A12%3A34%3A56 uuid%3A ip%3A192.168.1.10%3A39872 name%3AKitchen " +
+	"seq_no%3A0 model%3Areceiver modelname%3ASqueezebox%20Receiver power%3A1 isplaying%3A0 connected%3A1 firmware%3A77 " +
+	"playerindex%3A1 playerid%3Ab8%3A27%3Aeb%3A00%3A00%3A01 ip%3A192.168.1.11%3A41234 name%3ALiving%20room " +
+	"model%3Asqueezelite modelname%3ASqueezeLite connected%3A0 firmware%3Av1.9.8"
+
+var expectedPlayers = []Player{
+	{Id: "00:04:20:12:34:56", Name: "Kitchen", Model: "receiver", ModelName: "Squeezebox Receiver",
+		IP: "192.168.1.10", Firmware: "77", Connected: true},
+	{Id: "b8:27:eb:00:00:01", Name: "Living room", Model: "squeezelite", ModelName: "SqueezeLite",
+		IP: "192.168.1.11", Firmware: "v1.9.8", Connected: false},
+}
+
+func TestServer_RefreshPlayers(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("player count ?", "player count 2")
+	squeezeMock.SetResponse("players 0 2", rawPlayers)
+
+	server := New(squeezeMock.Addr())
+	err = server.RefreshPlayers()
+	if err != nil {
+		t.Errorf("unable to refresh players: %v", err)
+	}
+
+	players := server.Players()
+	if !reflect.DeepEqual(players, expectedPlayers) {
+		t.Errorf("bad players: %#v, wants %#v", players, expectedPlayers)
+	}
+
+	p, ok := server.Player("b8:27:eb:00:00:01")
+	if !ok || p.Name != "Living room" {
+		t.Errorf("bad player: %#v, wants %#v", p, expectedPlayers[1])
+	}
+}
+
+func TestServer_processClientEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("player count ?", "player count 2")
+	squeezeMock.SetResponse("players 0 2", rawPlayers)
+
+	server := New(squeezeMock.Addr())
+
+	go server.processEventLine("b8%3A27%3Aeb%3A00%3A00%3A01 client new\n")
+	players := <-server.NotifyPlayersChange()
+	if !reflect.DeepEqual(players, expectedPlayers) {
+		t.Errorf("bad players after new client: %#v, wants %#v", players, expectedPlayers)
+	}
+
+	go server.processEventLine("00%3A04%3A20%3A12%3A34%3A56 client disconnect\n")
+	players = <-server.NotifyPlayersChange()
+	if len(players) != 2 || players[0].Connected {
+		t.Errorf("player should be disconnected: %#v", players)
+	}
+
+	go server.processEventLine("00%3A04%3A20%3A12%3A34%3A56 client forget\n")
+	players = <-server.NotifyPlayersChange()
+	if len(players) != 1 || players[0].Id != "b8:27:eb:00:00:01" {
+		t.Errorf("player should be forgotten: %#v", players)
+	}
+}
@@ -0,0 +1,86 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+)
+
+// maxPlaylistTracks limit the size of playlist notifications
+const maxPlaylistTracks = 500
+
+type PlaylistTrack struct {
+	Index   int
+	Current bool
+	Track
+}
+
+type Playlist struct {
+	Player PlayerId
+	Tracks []PlaylistTrack
+}
+
+// Playlist read the tracks of the player queue, only the current one has its elapsed time
+func (s *Server) Playlist(id PlayerId) (*Playlist, error) {
+	values, err := s.query(id, "status", "0", strconv.Itoa(maxPlaylistTracks), "tags:"+statusTags)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch playlist: %v", err)
+	}
+	return s.decodePlaylist(id, values), nil
+}
+
+func (s *Server) decodePlaylist(id PlayerId, values []string) *Playlist {
+	// Skip the echoed '<playerid> status 0 N' fields
+	if len(values) > 4 {
+		values = values[4:]
+	}
+	fields, items := parseTaggedResponse(values, "playlist index")
+	current, hasCurrent := fields["playlist_cur_index"]
+
+	p := Playlist{Player: id, Tracks: make([]PlaylistTrack, 0, len(items))}
+	for _, item := range items {
+		st := newStatus(id, fields, item)
+		if _, ok := item["duration"]; !ok {
+			st.Duration = NilTrackDuration
+		}
+		isCurrent := hasCurrent && item["playlist index"] == current
+		if !isCurrent {
+			st.Time = NilTrackTime
+		}
+		p.Tracks = append(p.Tracks, PlaylistTrack{
+			Index:   parseInt(item["playlist index"]),
+			Current: isCurrent,
+			Track:   *s.track(st),
+		})
+	}
+	return &p
+}
+
+func (s *Server) NotifyPlaylistChange() <-chan *Playlist {
+	return s.chanPlaylist
+}
+
+// refreshPlaylist read the player queue after a playlist change
+func (s *Server) refreshPlaylist(id PlayerId) {
+	p, err := s.Playlist(id)
+	if err != nil {
+		log.Errorf("unable to read playlist of player %v: %v", id, err)
+		return
+	}
+	select {
+	case s.chanPlaylist <- p:
+	case <-s.done:
+	}
+}
+
+// isPlaylistEvent return true for 'playlist' events which change the queue content or its current track
+func isPlaylistEvent(fields []string) bool {
+	if len(fields) < 3 || fields[1] != "playlist" {
+		return false
+	}
+	switch fields[2] {
+	case "addtracks", "inserttracks", "loadtracks", "delete", "deletetracks", "move", "clear", "load", "shuffle", "newsong":
+		return true
+	}
+	return false
+}
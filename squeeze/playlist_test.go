@@ -0,0 +1,85 @@
+package squeeze
+
+import (
+	"strings"
+	"testing"
+)
+
+const rawPlaylist = "playerId status 0 500 tags%3AaAlgdytKcuNoIRr player_name%3AKitchen mode%3Aplay time%3A23.5 " +
+	"duration%3A150 playlist_cur_index%3A1 playlist_tracks%3A3 " +
+	"playlist%20index%3A0 id%3A1 title%3ATutti%20Frutti artist%3ALittle%20Richard duration%3A144 coverid%3Aa1 " +
+	"playlist%20index%3A1 id%3A2 title%3ALucille artist%3ALittle%20Richard duration%3A150 coverid%3Aa2 " +
+	"playlist%20index%3A2 id%3A3 title%3ASinnerman artist%3ANina%20Simone"
+
+func TestServer_Playlist(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("playerId status 0 500 tags:"+statusTags, rawPlaylist)
+
+	server := New("127.0.0.1:9090")
+	server.transport = newCliClient(squeezeMock.Addr())
+	p, err := server.Playlist(playerId)
+	if err != nil {
+		t.Fatalf("unable to read playlist: %v", err)
+	}
+
+	expected := []PlaylistTrack{
+		{Index: 0, Track: Track{Title: "Tutti Frutti", Artist: "Little Richard", Duration: 144,
+			ArtworkUrl: "http://127.0.0.1:9000/music/a1/cover.jpg"}},
+		{Index: 1, Current: true, Track: Track{Title: "Lucille", Artist: "Little Richard", Duration: 150, CurrentTime: 23.5,
+			ArtworkUrl: "http://127.0.0.1:9000/music/a2/cover.jpg"}},
+		{Index: 2, Track: Track{Title: "Sinnerman", Artist: "Nina Simone"}},
+	}
+	if p.Player != playerId || len(p.Tracks) != len(expected) {
+		t.Fatalf("bad playlist: %#v", *p)
+	}
+	for i := range expected {
+		if p.Tracks[i] != expected[i] {
+			t.Errorf("bad track %d: %#v, wants %#v", i, p.Tracks[i], expected[i])
+		}
+	}
+}
+
+func TestServer_onPlaylistEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("playerId status 0 500 tags:"+statusTags, rawPlaylist)
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("playerId playlist addtracks listRef%3Aartist\n")
+
+	p := <-server.NotifyPlaylistChange()
+	if p.Player != playerId || len(p.Tracks) != 3 {
+		t.Errorf("bad playlist: %#v", *p)
+	}
+}
+
+func Test_isPlaylistEvent(t *testing.T) {
+	cases := []struct {
+		event    string
+		expected bool
+	}{
+		{"playerId playlist addtracks", true},
+		{"playerId playlist delete", true},
+		{"playerId playlist move 1 2", true},
+		{"playerId playlist clear", true},
+		{"playerId playlist load", true},
+		{"playerId playlist newsong Lucille 3", true},
+		{"playerId playlist pause 1", false},
+		{"playerId mixer volume 10", false},
+		{"playerId playlist", false},
+	}
+	for _, c := range cases {
+		if isPlaylistEvent(strings.Fields(c.event)) != c.expected {
+			t.Errorf("[%v] bad result, wants %v", c.event, c.expected)
+		}
+	}
+}
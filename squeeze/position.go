@@ -0,0 +1,54 @@
+package squeeze
+
+import (
+	"fmt"
+	"time"
+)
+
+type Position struct {
+	Player    PlayerId
+	Elapsed   float64
+	Duration  float64
+	Remaining float64
+	// Progress is the elapsed percent of the track, 0 when duration is unknown as for radio streams
+	Progress float64
+	// Timestamp is the unix time in milliseconds when the position was read
+	Timestamp int64
+}
+
+// Position read elapsed time and duration of the current track from a single status query
+func (s *Server) Position(id PlayerId) (*Position, error) {
+	values, err := s.query(id, "status", "-", "1", "tags:d")
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch position: %v", err)
+	}
+	p := Position{Player: id, Timestamp: time.Now().UnixNano() / int64(time.Millisecond)}
+
+	status := decodeStatus(id, values)
+	if status.Time > 0 {
+		p.Elapsed = float64(status.Time)
+	}
+	if status.Duration > 0 {
+		p.Duration = float64(status.Duration)
+		p.Remaining = p.Duration - p.Elapsed
+		if p.Remaining < 0 {
+			p.Remaining = 0
+		}
+		p.Progress = 100 * p.Elapsed / p.Duration
+	}
+	return &p, nil
+}
+
+// PlayingPlayers return the players known as playing
+func (s *Server) PlayingPlayers() []PlayerId {
+	s.muPlayers.Lock()
+	defer s.muPlayers.Unlock()
+
+	ids := make([]PlayerId, 0)
+	for id, state := range s.playback {
+		if state == Playing {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
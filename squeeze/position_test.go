@@ -0,0 +1,62 @@
+package squeeze
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestServer_Position(t *testing.T) {
+	cases := []struct {
+		name              string
+		rawTime           float64
+		rawDuration       float64
+		expectedElapsed   float64
+		expectedRemaining float64
+		expectedProgress  float64
+	}{
+		{"Track", 60, 240, 60, 180, 25},
+		{"Radio", 3600, 0, 3600, 0, 0},
+		{"Time after end", 250, 240, 250, 0, 104.16666666666667},
+	}
+
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+
+	server := New(squeezeMock.Addr())
+	for _, c := range cases {
+		squeezeMock.SetResponse("playerId status - 1 tags:d",
+			fmt.Sprintf("playerId status - 1 tags%%3Ad mode%%3Aplay time%%3A%v duration%%3A%v", c.rawTime, c.rawDuration))
+
+		before := time.Now().UnixNano() / int64(time.Millisecond)
+		p, err := server.Position(playerId)
+		if err != nil {
+			t.Errorf("[%v] unable to read position: %v", c.name, err)
+			continue
+		}
+		if p.Player != playerId || p.Elapsed != c.expectedElapsed || p.Remaining != c.expectedRemaining ||
+			math.Abs(p.Progress-c.expectedProgress) > 0.001 {
+			t.Errorf("[%v] bad position: %#v", c.name, *p)
+		}
+		if p.Timestamp < before {
+			t.Errorf("[%v] bad timestamp: %v", c.name, p.Timestamp)
+		}
+	}
+}
+
+func TestServer_PlayingPlayers(t *testing.T) {
+	server := New("127.0.0.1:9090")
+	server.playback["p1"] = Playing
+	server.playback["p2"] = Paused
+	server.playback["p3"] = Stopped
+
+	ids := server.PlayingPlayers()
+	if len(ids) != 1 || ids[0] != "p1" {
+		t.Errorf("bad playing players: %#v", ids)
+	}
+}
@@ -0,0 +1,53 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+type Power struct {
+	Player PlayerId
+	On     bool
+}
+
+// Power query the power state of the player
+func (s *Server) Power(id PlayerId) (bool, error) {
+	values, err := s.query(id, "power", "?")
+	if err != nil {
+		return false, fmt.Errorf("unable to fetch power: %v", err)
+	}
+	if len(values) < 3 {
+		return false, fmt.Errorf("no power value in response %v", values)
+	}
+	return values[2] == "1", nil
+}
+
+func (s *Server) NotifyPowerChange() <-chan *Power {
+	return s.chanPower
+}
+
+// setPower register the player power state and notify it when changed
+func (s *Server) setPower(id PlayerId, on bool) {
+	s.muPlayers.Lock()
+	previous, ok := s.power[id]
+	s.power[id] = on
+	s.muPlayers.Unlock()
+
+	if ok && previous == on {
+		return
+	}
+	select {
+	case s.chanPower <- &Power{Player: id, On: on}:
+	case <-s.done:
+	}
+}
+
+// refreshPower read the power state after a 'power' event
+func (s *Server) refreshPower(id PlayerId) {
+	on, err := s.Power(id)
+	if err != nil {
+		log.Errorf("unable to read power of player %v: %v", id, err)
+		return
+	}
+	s.setPower(id, on)
+}
@@ -0,0 +1,87 @@
+package squeeze
+
+import (
+	"testing"
+)
+
+func TestServer_Power(t *testing.T) {
+	cases := []struct {
+		name       string
+		response   string
+		expectedOn bool
+	}{
+		{"On", "playerId power 1", true},
+		{"Off", "playerId power 0", false},
+	}
+
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	for _, c := range cases {
+		squeezeMock.SetResponse("playerId power ?", c.response)
+		on, err := server.Power(playerId)
+		if err != nil {
+			t.Errorf("[%v] unable to read power: %v", c.name, err)
+			continue
+		}
+		if on != c.expectedOn {
+			t.Errorf("[%v] bad power: %v, wants %v", c.name, on, c.expectedOn)
+		}
+	}
+}
+
+func TestServer_onPowerEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("playerId sleep ?", "playerId sleep 0")
+
+	server := New(squeezeMock.Addr())
+	// Sleep timer is read again after power events
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-server.NotifySleepChange():
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	events := []struct {
+		line       string
+		response   string
+		expectedOn bool
+	}{
+		{"playerId power 1\n", "playerId power 1", true},
+		{"playerId power\n", "playerId power 0", false},
+	}
+	for _, e := range events {
+		squeezeMock.SetResponse("playerId power ?", e.response)
+		go server.processEventLine(e.line)
+		p := <-server.NotifyPowerChange()
+		if p.Player != playerId || p.On != e.expectedOn {
+			t.Errorf("bad power after '%v' event: %#v", e.line, *p)
+		}
+	}
+
+	// Unchanged power isn't notified
+	done := make(chan struct{})
+	go func() {
+		server.processEventLine("playerId power 0\n")
+		close(done)
+	}()
+	select {
+	case p := <-server.NotifyPowerChange():
+		t.Errorf("unexpected power notification: %#v", *p)
+	case <-done:
+	}
+}
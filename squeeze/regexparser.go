@@ -0,0 +1,170 @@
+package squeeze
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Metadata extracted by regex parsers, as named groups or template keys
+const (
+	MetadataArtist = "artist"
+	MetadataAlbum  = "album"
+	MetadataTitle  = "title"
+	MetadataGenre  = "genre"
+	MetadataYear   = "year"
+)
+
+var metadataNames = []string{MetadataArtist, MetadataAlbum, MetadataTitle, MetadataGenre, MetadataYear}
+
+// RegexParserConfig declare a parser which extracts metadata from raw status fields
+type RegexParserConfig struct {
+	Name     string         `json:"name" yaml:"name"`
+	Patterns []FieldPattern `json:"patterns" yaml:"patterns"`
+}
+
+// FieldPattern apply a regex to a raw status field, as 'title' or 'current_title'.
+// Metadata are read from the groups of the same name, or built from templates with '${group}' references.
+type FieldPattern struct {
+	Field     string            `json:"field" yaml:"field"`
+	Regex     string            `json:"regex" yaml:"regex"`
+	Templates map[string]string `json:"templates,omitempty" yaml:"templates,omitempty"`
+}
+
+type fieldPattern struct {
+	field     string
+	regex     *regexp.Regexp
+	templates map[string]string
+}
+
+// RegexParser return the first non empty value extracted by its patterns, in order,
+// and fallback to the default parser for metadata without match
+type RegexParser struct {
+	patterns []fieldPattern
+	fallback DefaultParser
+}
+
+func NewRegexParser(config RegexParserConfig) (*RegexParser, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("parser name is missing")
+	}
+	p := RegexParser{patterns: make([]fieldPattern, 0, len(config.Patterns))}
+	for i, fp := range config.Patterns {
+		if fp.Field == "" {
+			return nil, fmt.Errorf("parser %v, pattern %d: field is missing", config.Name, i+1)
+		}
+		regex, err := regexp.Compile(fp.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("parser %v, pattern %d: invalid regex: %v", config.Name, i+1, err)
+		}
+		for name := range fp.Templates {
+			if !isMetadataName(name) {
+				return nil, fmt.Errorf("parser %v, pattern %d: unknown template '%v', wants one of %v",
+					config.Name, i+1, name, metadataNames)
+			}
+		}
+		if !extractsMetadata(regex, fp.Templates) {
+			return nil, fmt.Errorf("parser %v, pattern %d: no group or template named as %v",
+				config.Name, i+1, metadataNames)
+		}
+		p.patterns = append(p.patterns, fieldPattern{field: fp.Field, regex: regex, templates: fp.Templates})
+	}
+	return &p, nil
+}
+
+func isMetadataName(name string) bool {
+	for _, n := range metadataNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func extractsMetadata(regex *regexp.Regexp, templates map[string]string) bool {
+	if len(templates) > 0 {
+		return true
+	}
+	for _, name := range regex.SubexpNames() {
+		if isMetadataName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// extract return the first non empty value of the metadata
+func (p *RegexParser) extract(name string, status *Status) (string, bool) {
+	for _, fp := range p.patterns {
+		raw, ok := status.TrackFields[fp.field]
+		if !ok {
+			raw = status.Fields[fp.field]
+		}
+		match := fp.regex.FindStringSubmatchIndex(raw)
+		if match == nil {
+			continue
+		}
+
+		var value string
+		if tmpl, ok := fp.templates[name]; ok {
+			value = string(fp.regex.ExpandString(nil, tmpl, raw, match))
+		} else {
+			for i, group := range fp.regex.SubexpNames() {
+				if group == name && match[2*i] >= 0 {
+					value = raw[match[2*i]:match[2*i+1]]
+					break
+				}
+			}
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (p *RegexParser) Artist(status *Status) string {
+	if v, ok := p.extract(MetadataArtist, status); ok {
+		return v
+	}
+	return p.fallback.Artist(status)
+}
+
+func (p *RegexParser) Album(status *Status) string {
+	if v, ok := p.extract(MetadataAlbum, status); ok {
+		return v
+	}
+	return p.fallback.Album(status)
+}
+
+func (p *RegexParser) Title(status *Status) string {
+	if v, ok := p.extract(MetadataTitle, status); ok {
+		return v
+	}
+	return p.fallback.Title(status)
+}
+
+func (p *RegexParser) Genre(status *Status) string {
+	if v, ok := p.extract(MetadataGenre, status); ok {
+		return v
+	}
+	return p.fallback.Genre(status)
+}
+
+func (p *RegexParser) Year(status *Status) int {
+	if v, ok := p.extract(MetadataYear, status); ok {
+		if year, err := strconv.Atoi(v); err == nil {
+			return year
+		}
+	}
+	return p.fallback.Year(status)
+}
+
+func (p *RegexParser) Duration(status *Status) TrackDuration {
+	return p.fallback.Duration(status)
+}
+
+func (p *RegexParser) Time(status *Status) TrackTime {
+	return p.fallback.Time(status)
+}
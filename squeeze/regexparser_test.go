@@ -0,0 +1,78 @@
+package squeeze
+
+import (
+	"testing"
+)
+
+func TestRegexParser(t *testing.T) {
+	streamParser, err := NewRegexParser(RegexParserConfig{
+		Name: "stream",
+		Patterns: []FieldPattern{
+			{Field: "title", Regex: `^(?P<artist>.+?) - (?P<title>.+?)(?: \((?P<year>\d{4})\))?$`},
+			{Field: "album", Regex: `^(?P<album>.+?)\s*/\s*(?P<label>[^/]+?)\s*/\s*(?P<year>\d{4})$`,
+				Templates: map[string]string{MetadataAlbum: "${album} (${label})"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to build parser: %v", err)
+	}
+
+	cases := []struct {
+		name          string
+		status        Status
+		expectedTrack Track
+	}{
+		{"Artist - Title (Year)",
+			Status{Title: "Nina Simone - Sinnerman (1965)", TrackFields: map[string]string{"title": "Nina Simone - Sinnerman (1965)"},
+				Genre: "Jazz", Time: 10, Duration: NilTrackDuration},
+			Track{Artist: "Nina Simone", Title: "Sinnerman", Genre: "Jazz", Year: 1965, CurrentTime: 10},
+		},
+		{"Without year",
+			Status{TrackFields: map[string]string{"title": "Nina Simone - Sinnerman"}, Year: 2001,
+				Time: NilTrackTime, Duration: NilTrackDuration},
+			Track{Artist: "Nina Simone", Title: "Sinnerman", Year: 2001},
+		},
+		{"Album with label template",
+			Status{Title: "Lucille", Artist: "Little Richard", Album: "Here's Little Richard / Specialty / 1957",
+				TrackFields: map[string]string{"album": "Here's Little Richard / Specialty / 1957", "title": "Lucille"},
+				Time:        NilTrackTime, Duration: 150},
+			Track{Artist: "Little Richard", Album: "Here's Little Richard (Specialty)", Title: "Lucille", Year: 1957, Duration: 150},
+		},
+		{"Player field",
+			Status{Fields: map[string]string{"title": "A - B"}, Time: NilTrackTime, Duration: NilTrackDuration},
+			Track{Artist: "A", Title: "B"},
+		},
+		{"Fallback",
+			Status{Artist: "Artist", Title: "Title", Album: "Album", TrackFields: map[string]string{"title": "Title", "album": "Album"},
+				Time: NilTrackTime, Duration: NilTrackDuration},
+			Track{Artist: "Artist", Title: "Title", Album: "Album"},
+		},
+	}
+
+	for _, c := range cases {
+		track := ParseTrack(streamParser, &c.status)
+		if *track != c.expectedTrack {
+			t.Errorf("[%v] bad track: %#v, wants %#v", c.name, *track, c.expectedTrack)
+		}
+	}
+}
+
+func TestNewRegexParserError(t *testing.T) {
+	cases := []struct {
+		name   string
+		config RegexParserConfig
+	}{
+		{"No name", RegexParserConfig{Patterns: []FieldPattern{{Field: "title", Regex: "(?P<title>.*)"}}}},
+		{"No field", RegexParserConfig{Name: "p", Patterns: []FieldPattern{{Regex: "(?P<title>.*)"}}}},
+		{"Invalid regex", RegexParserConfig{Name: "p", Patterns: []FieldPattern{{Field: "title", Regex: "(?P<title>"}}}},
+		{"No metadata group", RegexParserConfig{Name: "p", Patterns: []FieldPattern{{Field: "title", Regex: "(?P<name>.*)"}}}},
+		{"Unknown template", RegexParserConfig{Name: "p", Patterns: []FieldPattern{
+			{Field: "title", Regex: "(?P<name>.*)", Templates: map[string]string{"label": "${name}"}}}}},
+	}
+
+	for _, c := range cases {
+		if _, err := NewRegexParser(c.config); err == nil {
+			t.Errorf("[%v] config should be rejected", c.name)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package squeeze
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultParserName     = "default"
+	RadioFranceParserName = "radiofrance"
+)
+
+var (
+	muParsers sync.Mutex
+	parsers   = map[string]MetadataParser{
+		DefaultParserName:     DefaultParser{},
+		RadioFranceParserName: RadioFranceParser{},
+	}
+)
+
+// RegisterParser make a parser available to the parser rules under name, an existing parser is replaced
+func RegisterParser(name string, p MetadataParser) {
+	muParsers.Lock()
+	defer muParsers.Unlock()
+	parsers[name] = p
+}
+
+func lookupParser(name string) (MetadataParser, bool) {
+	muParsers.Lock()
+	defer muParsers.Unlock()
+	p, ok := parsers[name]
+	return p, ok
+}
+
+// ParserNames return the registered parser names, sorted
+func ParserNames() []string {
+	muParsers.Lock()
+	defer muParsers.Unlock()
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fields matched by parser rules
+const (
+	RuleCurrentTitle = "current_title"
+	RuleUrl          = "url"
+	RuleRemote       = "remote"
+	RulePlayer       = "player"
+)
+
+// ParserRule select the parser of a track when the status field matches the prefix or the regex
+type ParserRule struct {
+	Field  string `json:"field" yaml:"field"`
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty" yaml:"regex,omitempty"`
+	Parser string `json:"parser" yaml:"parser"`
+}
+
+// DefaultParserRules keep the historical behavior: Radio France streams have the year in the album field
+var DefaultParserRules = []ParserRule{
+	{Field: RuleCurrentTitle, Prefix: "fip", Parser: RadioFranceParserName},
+	{Field: RuleCurrentTitle, Prefix: "FIP", Parser: RadioFranceParserName},
+}
+
+type parserRule struct {
+	field  string
+	prefix string
+	regex  *regexp.Regexp
+	parser MetadataParser
+}
+
+func compileParserRules(rules []ParserRule) ([]parserRule, error) {
+	compiled := make([]parserRule, 0, len(rules))
+	for i, r := range rules {
+		switch r.Field {
+		case RuleCurrentTitle, RuleUrl, RuleRemote, RulePlayer:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown field '%v', wants one of %v, %v, %v or %v",
+				i+1, r.Field, RuleCurrentTitle, RuleUrl, RuleRemote, RulePlayer)
+		}
+		if (r.Prefix == "") == (r.Regex == "") {
+			return nil, fmt.Errorf("rule %d: either prefix or regex must be defined", i+1)
+		}
+		p, ok := lookupParser(r.Parser)
+		if !ok {
+			return nil, fmt.Errorf("rule %d: unknown parser '%v', wants one of %v", i+1, r.Parser, ParserNames())
+		}
+
+		rule := parserRule{field: r.Field, prefix: r.Prefix, parser: p}
+		if r.Regex != "" {
+			regex, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: invalid regex: %v", i+1, err)
+			}
+			rule.regex = regex
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+func (r parserRule) match(status *Status) bool {
+	var value string
+	switch r.field {
+	case RuleCurrentTitle:
+		value = status.CurrentTitle
+	case RuleUrl:
+		value = status.Url
+	case RuleRemote:
+		value = "0"
+		if status.Remote {
+			value = "1"
+		}
+	case RulePlayer:
+		value = string(status.Player)
+	}
+	if r.regex != nil {
+		return r.regex.MatchString(value)
+	}
+	return strings.HasPrefix(value, r.prefix)
+}
+
+// SetParserRules replace the rules used to select the parser of tracks, the first matching rule wins
+// and DefaultParser is used when no rule matches
+func (s *Server) SetParserRules(rules []ParserRule) error {
+	compiled, err := compileParserRules(rules)
+	if err != nil {
+		return err
+	}
+	s.muParsers.Lock()
+	defer s.muParsers.Unlock()
+	s.parserRules = compiled
+	return nil
+}
+
+func (s *Server) metadataParser(status *Status) MetadataParser {
+	s.muParsers.Lock()
+	defer s.muParsers.Unlock()
+	for _, r := range s.parserRules {
+		if r.match(status) {
+			return r.parser
+		}
+	}
+	return DefaultParser{}
+}
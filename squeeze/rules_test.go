@@ -0,0 +1,87 @@
+package squeeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+type upperTitleParser struct {
+	DefaultParser
+}
+
+func (p upperTitleParser) Title(status *Status) string {
+	return "TITLE"
+}
+
+func Test_compileParserRules(t *testing.T) {
+	cases := []struct {
+		name        string
+		rules       []ParserRule
+		expectedErr bool
+	}{
+		{"Default rules", DefaultParserRules, false},
+		{"Regex", []ParserRule{{Field: RuleUrl, Regex: "radiofrance\\.fr", Parser: RadioFranceParserName}}, false},
+		{"Unknown field", []ParserRule{{Field: "artist", Prefix: "x", Parser: DefaultParserName}}, true},
+		{"Unknown parser", []ParserRule{{Field: RuleUrl, Prefix: "x", Parser: "unknown"}}, true},
+		{"No matcher", []ParserRule{{Field: RuleUrl, Parser: DefaultParserName}}, true},
+		{"Prefix and regex", []ParserRule{{Field: RuleUrl, Prefix: "x", Regex: "x", Parser: DefaultParserName}}, true},
+		{"Invalid regex", []ParserRule{{Field: RuleUrl, Regex: "(", Parser: DefaultParserName}}, true},
+	}
+
+	for _, c := range cases {
+		_, err := compileParserRules(c.rules)
+		if (err != nil) != c.expectedErr {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+	}
+}
+
+func TestServer_metadataParser(t *testing.T) {
+	RegisterParser("upper", upperTitleParser{})
+	rules := []ParserRule{
+		{Field: RulePlayer, Prefix: "00:04:20", Parser: "upper"},
+		{Field: RuleCurrentTitle, Regex: "(?i)^(fip|france inter)", Parser: RadioFranceParserName},
+		{Field: RuleUrl, Prefix: "http://icecast.radiofrance.fr/", Parser: RadioFranceParserName},
+		{Field: RuleRemote, Prefix: "1", Parser: "upper"},
+	}
+
+	cases := []struct {
+		name           string
+		rules          []ParserRule
+		status         Status
+		expectedParser MetadataParser
+	}{
+		{"Default FIP", DefaultParserRules, Status{CurrentTitle: "FIP"}, RadioFranceParser{}},
+		{"Default fip", DefaultParserRules, Status{CurrentTitle: "fipelectro-midfi.mp3"}, RadioFranceParser{}},
+		{"Default other", DefaultParserRules, Status{CurrentTitle: "France Inter"}, DefaultParser{}},
+		{"Player", rules, Status{Player: "00:04:20:12:34:56", CurrentTitle: "FIP"}, upperTitleParser{}},
+		{"Current title regex", rules, Status{CurrentTitle: "France Inter"}, RadioFranceParser{}},
+		{"Url", rules, Status{Url: "http://icecast.radiofrance.fr/franceculture-midfi.mp3"}, RadioFranceParser{}},
+		{"Remote", rules, Status{Remote: true}, upperTitleParser{}},
+		{"No match", rules, Status{CurrentTitle: "Local file"}, DefaultParser{}},
+		{"No rules", []ParserRule{}, Status{CurrentTitle: "FIP"}, DefaultParser{}},
+	}
+
+	server := New("127.0.0.1:9090")
+	for _, c := range cases {
+		if err := server.SetParserRules(c.rules); err != nil {
+			t.Fatalf("[%v] unable to set rules: %v", c.name, err)
+		}
+		p := server.metadataParser(&c.status)
+		if !reflect.DeepEqual(p, c.expectedParser) {
+			t.Errorf("[%v] bad parser: %#v, wants %#v", c.name, p, c.expectedParser)
+		}
+	}
+}
+
+func TestServer_SetParserRulesError(t *testing.T) {
+	server := New("127.0.0.1:9090")
+	err := server.SetParserRules([]ParserRule{{Field: RuleUrl, Prefix: "x", Parser: "unknown"}})
+	if err == nil {
+		t.Fatalf("unknown parser should be rejected")
+	}
+	// Previous rules are kept
+	if p := server.metadataParser(&Status{CurrentTitle: "FIP"}); !reflect.DeepEqual(p, RadioFranceParser{}) {
+		t.Errorf("bad parser: %#v", p)
+	}
+}
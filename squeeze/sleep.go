@@ -0,0 +1,65 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+type Sleep struct {
+	Player PlayerId
+	// Remaining is the number of seconds before the player sleeps, 0 when there is no sleep timer
+	Remaining float64
+	// Timestamp is the unix time in milliseconds when the remaining time was read
+	Timestamp int64
+}
+
+// Sleep query the remaining time of the player sleep timer
+func (s *Server) Sleep(id PlayerId) (*Sleep, error) {
+	values, err := s.query(id, "sleep", "?")
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch sleep timer: %v", err)
+	}
+	if len(values) < 3 {
+		return nil, fmt.Errorf("no sleep value in response %v", values)
+	}
+	return newSleep(id, parseFloat(values[2], 0), time.Now()), nil
+}
+
+func newSleep(id PlayerId, remaining float64, now time.Time) *Sleep {
+	return &Sleep{Player: id, Remaining: remaining, Timestamp: now.UnixNano() / int64(time.Millisecond)}
+}
+
+// SetSleep put the player to sleep after the given number of seconds
+func (s *Server) SetSleep(id PlayerId, seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("invalid sleep time %v, must be positive", seconds)
+	}
+	return s.Command(id, "sleep", strconv.Itoa(seconds))
+}
+
+func (s *Server) CancelSleep(id PlayerId) error {
+	return s.Command(id, "sleep", "0")
+}
+
+func (s *Server) NotifySleepChange() <-chan *Sleep {
+	return s.chanSleep
+}
+
+// refreshSleep read the remaining time of the sleep timer after a 'sleep' event
+func (s *Server) refreshSleep(id PlayerId) {
+	sl, err := s.Sleep(id)
+	if err != nil {
+		log.Errorf("unable to read sleep timer of player %v: %v", id, err)
+		return
+	}
+	s.notifySleep(sl)
+}
+
+func (s *Server) notifySleep(sl *Sleep) {
+	select {
+	case s.chanSleep <- sl:
+	case <-s.done:
+	}
+}
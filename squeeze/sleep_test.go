@@ -0,0 +1,86 @@
+package squeeze
+
+import (
+	"testing"
+)
+
+func TestServer_Sleep(t *testing.T) {
+	cases := []struct {
+		name              string
+		response          string
+		expectedRemaining float64
+	}{
+		{"Sleep timer", "playerId sleep 1785.4", 1785.4},
+		{"No sleep timer", "playerId sleep 0", 0},
+	}
+
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	for _, c := range cases {
+		squeezeMock.SetResponse("playerId sleep ?", c.response)
+		sl, err := server.Sleep(playerId)
+		if err != nil {
+			t.Errorf("[%v] unable to read sleep timer: %v", c.name, err)
+			continue
+		}
+		if sl.Player != playerId || sl.Remaining != c.expectedRemaining || sl.Timestamp == 0 {
+			t.Errorf("[%v] bad sleep timer: %#v, wants remaining %v", c.name, *sl, c.expectedRemaining)
+		}
+	}
+}
+
+func TestServer_SleepCommands(t *testing.T) {
+	cases := []struct {
+		name            string
+		command         func(s *Server) error
+		expectedCommand string
+	}{
+		{"Set", func(s *Server) error { return s.SetSleep(playerId, 1800) }, "playerId sleep 1800"},
+		{"Cancel", func(s *Server) error { return s.CancelSleep(playerId) }, "playerId sleep 0"},
+		{"Negative", func(s *Server) error { return s.SetSleep(playerId, -1) }, ""},
+	}
+
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	for _, c := range cases {
+		squeezeMock.ResetCommands()
+		err := c.command(server)
+		if (err != nil) != (c.expectedCommand == "") {
+			t.Errorf("[%v] unexpected error: %v", c.name, err)
+		}
+		commands := squeezeMock.Commands()
+		if c.expectedCommand == "" && len(commands) != 0 {
+			t.Errorf("[%v] no command expected: %#v", c.name, commands)
+		}
+		if c.expectedCommand != "" && (len(commands) != 1 || commands[0] != c.expectedCommand) {
+			t.Errorf("[%v] bad commands: %#v, wants %#v", c.name, commands, c.expectedCommand)
+		}
+	}
+}
+
+func TestServer_onSleepEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("playerId sleep ?", "playerId sleep 1799.9")
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("playerId sleep 1800\n")
+
+	sl := <-server.NotifySleepChange()
+	if sl.Player != playerId || sl.Remaining != 1799.9 {
+		t.Errorf("bad sleep timer: %#v", *sl)
+	}
+}
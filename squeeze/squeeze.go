@@ -2,47 +2,214 @@ package squeeze
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 type PlayerId string
 
-func New(address string) Server {
-	return Server{address: address, chanNotify: make(chan *Track)}
+// New use the cli port of the server, address is 'host:9090'
+func New(address string) *Server {
+	library := newCliClient(address)
+	library.timeout = libraryTimeout
+	s := newServer(address, newCliClient(address), library)
+	s.events = s.listenOnce
+	s.webUrl = defaultWebUrl(address)
+	return s
+}
+
+// NewJsonRpc use the JSON-RPC api of the web server at baseUrl, as 'http://host:9000', and its cometd api for events
+func NewJsonRpc(baseUrl string) *Server {
+	library := newJsonRpcClient(baseUrl)
+	library.client.Timeout = libraryTimeout
+	s := newServer(baseUrl, newJsonRpcClient(baseUrl), library)
+	s.events = s.cometdOnce
+	s.webUrl = strings.TrimRight(baseUrl, "/")
+	return s
+}
+
+func newServer(address string, t, library transport) *Server {
+	rules, err := compileParserRules(DefaultParserRules)
+	if err != nil {
+		log.Panicf("invalid default parser rules: %v", err)
+	}
+	return &Server{
+		parserRules:     rules,
+		httpClient:      &http.Client{Timeout: defaultRequestTimeout},
+		address:         address,
+		transport:       t,
+		library:         library,
+		chanNotify:      make(chan *PlayerTrack),
+		chanMixer:       make(chan *Mixer),
+		chanPlayers:     make(chan []Player),
+		chanState:       make(chan ConnectionState),
+		chanPlayback:    make(chan *Playback),
+		chanPlaylist:    make(chan *Playlist),
+		chanFavorites:   make(chan []Favorite),
+		chanSync:        make(chan []SyncGroup),
+		chanAlarms:      make(chan *Alarms),
+		chanAlarmEvents: make(chan *AlarmEvent),
+		chanSleep:       make(chan *Sleep),
+		chanPower:       make(chan *Power),
+		players:         make(map[PlayerId]*Player),
+		playback:        make(map[PlayerId]PlaybackState),
+		leaders:         make(map[PlayerId]PlayerId),
+		power:           make(map[PlayerId]bool),
+		state:           Disconnected,
+		minBackoff:      defaultMinBackoff,
+		maxBackoff:      defaultMaxBackoff,
+		done:            make(chan struct{}),
+	}
 }
 
 type Server struct {
-	address    string
-	chanNotify chan *Track
-	DefaultCurrentTitleParser
+	address   string
+	transport transport
+	// library is a dedicated transport for slow library queries, they don't delay nor break player requests
+	library         transport
+	events          func() (bool, error)
+	chanNotify      chan *PlayerTrack
+	chanMixer       chan *Mixer
+	chanPlayers     chan []Player
+	chanState       chan ConnectionState
+	chanPlayback    chan *Playback
+	chanPlaylist    chan *Playlist
+	chanFavorites   chan []Favorite
+	chanSync        chan []SyncGroup
+	chanAlarms      chan *Alarms
+	chanAlarmEvents chan *AlarmEvent
+	chanSleep       chan *Sleep
+	chanPower       chan *Power
+
+	muParsers   sync.Mutex
+	parserRules []parserRule
+
+	muFavorites sync.Mutex
+	favorites   []Favorite
+
+	muPlayers sync.Mutex
+	players   map[PlayerId]*Player
+	playback  map[PlayerId]PlaybackState
+	leaders   map[PlayerId]PlayerId
+	power     map[PlayerId]bool
+
+	muState    sync.Mutex
+	state      ConnectionState
+	conn       io.Closer
+	username   string
+	password   string
+	webUrl     string
+	httpClient *http.Client
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	done       chan struct{}
+	closeOnce  sync.Once
 }
 
+// Close stop listening events, notification channels are closed when Listen returns
 func (s *Server) Close() error {
-	close(s.chanNotify)
-	return nil
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.muState.Lock()
+		defer s.muState.Unlock()
+		if s.conn != nil {
+			err = s.conn.Close()
+		}
+		for _, t := range []transport{s.transport, s.library} {
+			if tErr := t.Close(); tErr != nil && err == nil {
+				err = tErr
+			}
+		}
+	})
+	return err
 }
 
+// Listen read server events until Close is called, connection is restored with an exponential backoff when lost.
+// It stops with an error wrapping ErrAuthentication when the server rejects the credentials maxAuthFailures times in a row.
 func (s *Server) Listen() error {
+	defer func() {
+		close(s.chanNotify)
+		close(s.chanMixer)
+		close(s.chanPlayers)
+		close(s.chanState)
+		close(s.chanPlayback)
+		close(s.chanPlaylist)
+		close(s.chanFavorites)
+		close(s.chanSync)
+		close(s.chanAlarms)
+		close(s.chanAlarmEvents)
+		close(s.chanSleep)
+		close(s.chanPower)
+	}()
+
+	backoff := s.minBackoff
+	authFailures := 0
+	for {
+		connected, err := s.events()
+		s.setState(Disconnected)
+		if s.isClosed() {
+			return nil
+		}
+		if errors.Is(err, ErrAuthentication) {
+			authFailures++
+			if authFailures >= maxAuthFailures {
+				return err
+			}
+		} else {
+			authFailures = 0
+		}
+		if connected {
+			backoff = s.minBackoff
+		}
 
-	conn, err := connect(s.address)
+		delay := jitter(backoff)
+		log.Warnf("connection to %v lost: %v, retry in %v", s.address, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-s.done:
+			return nil
+		}
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// listenOnce open an event connection and process events until it is lost
+func (s *Server) listenOnce() (bool, error) {
+	s.setState(Connecting)
+	username, password := s.credentials()
+	conn, err := dial(s.address, username, password)
 	if err != nil {
-		return fmt.Errorf("unable to connect to %v", s.address)
+		return false, fmt.Errorf("unable to connect to %v: %w", s.address, err)
 	}
 	defer func() {
 		err := conn.Close()
-		if err != nil {
+		if err != nil && !s.isClosed() {
 			log.Warnf("unable to close connection to server %v: %v", s.address, err)
 		}
 	}()
+	if !s.setConn(conn) {
+		return false, fmt.Errorf("server closed")
+	}
+	defer s.setConn(nil)
 
 	_, err = fmt.Fprintf(conn, "listen 1\n")
 	if err != nil {
-		return fmt.Errorf("unable to send 'listen' command to server: %v", err)
+		return false, fmt.Errorf("unable to send 'listen' command to server: %v", err)
 	}
+	s.setState(Connected)
+	s.resync()
 
 	lms := bufio.NewReader(conn)
 	for {
@@ -50,30 +217,102 @@ func (s *Server) Listen() error {
 		if err != nil {
 			if err == io.EOF {
 				log.Debugf("connection to server close: %v", err)
-				break
+				return true, fmt.Errorf("connection closed by server")
 			}
-			log.Errorf("unable to read response: %v", err)
-			continue
+			return true, fmt.Errorf("unable to read event: %v", err)
 		}
 		line := strings.Trim(rawLine, "\r")
 		s.processEventLine(line)
 	}
+}
 
-	return nil
+// resync publish the whole players state, events missed while disconnected are lost
+func (s *Server) resync() {
+	if err := s.RefreshPlayers(); err != nil {
+		log.Errorf("unable to list players: %v", err)
+		return
+	}
+	players := s.Players()
+	select {
+	case s.chanPlayers <- players:
+	case <-s.done:
+		return
+	}
+	s.refreshFavorites()
+	s.updateSyncGroups()
+
+	for _, p := range players {
+		if !p.Connected {
+			continue
+		}
+		s.refreshPower(p.Id)
+		s.refreshPlaybackState(p.Id)
+		t, err := s.playerTrack(p.Id)
+		if err != nil {
+			log.Errorf("unable to resync track of player %v: %v", p.Id, err)
+		} else {
+			s.notifyTrack(t)
+		}
+		m, err := s.Mixer(p.Id)
+		if err != nil {
+			log.Errorf("unable to resync mixer of player %v: %v", p.Id, err)
+		} else {
+			s.notifyMixer(m)
+		}
+		s.refreshPlaylist(p.Id)
+		s.refreshAlarms(p.Id)
+		s.refreshSleep(p.Id)
+	}
 }
 
 func (s *Server) processEventLine(line string) {
 	log.Infof("new event: %v", line)
-	if strings.Contains(line, " newmetadata\n") ||
-		strings.Contains(line, " newsong ") {
+	fields := strings.Fields(line)
+	switch {
+	case strings.Contains(line, " newmetadata\n") || strings.Contains(line, " newsong "):
 		s.onNewMetadata(line)
+		if isPlaylistEvent(fields) {
+			s.refreshPlaylist(parsePlayerId(line))
+		}
+	case len(fields) > 2 && fields[1] == "mixer":
+		s.onMixer(line)
+	case len(fields) > 2 && fields[1] == "client":
+		s.onClient(line, fields[2])
+	case isPlaybackEvent(fields):
+		s.refreshPlayback(parsePlayerId(line))
+	case isPlaylistEvent(fields):
+		s.refreshPlaylist(parsePlayerId(line))
+	case len(fields) > 2 && fields[1] == "alarm":
+		s.onAlarm(parsePlayerId(line), fields)
+	case len(fields) > 1 && fields[1] == "power":
+		// The sleep timer ends by powering off the player
+		id := parsePlayerId(line)
+		s.refreshPower(id)
+		s.refreshSleep(id)
+	case len(fields) > 2 && fields[1] == "sleep":
+		s.refreshSleep(parsePlayerId(line))
+	case isSyncEvent(fields):
+		s.refreshSyncGroups()
+	case len(fields) > 1 && fields[0] == "favorites" && fields[1] == "changed":
+		s.refreshFavorites()
 	}
 }
 
-func (s *Server) NotifyTrackChange() <-chan *Track {
+func (s *Server) NotifyTrackChange() <-chan *PlayerTrack {
 	return s.chanNotify
 }
 
+// parsePlayerId read the player id from the first field of an event line
+func parsePlayerId(line string) PlayerId {
+	rawId := strings.SplitN(strings.Trim(line, "\n"), " ", 2)[0]
+	id, err := url.QueryUnescape(rawId)
+	if err != nil {
+		log.Warnf("unable to unescape player id \"%v\": %v", rawId, err)
+		return PlayerId(rawId)
+	}
+	return PlayerId(id)
+}
+
 type Track struct {
 	Artist      string
 	Album       string
@@ -82,100 +321,54 @@ type Track struct {
 	Year        int
 	CurrentTime float64
 	Duration    float64
+	ArtworkUrl  string
 }
 
-func (s *Server) CurrentTrack(id PlayerId) (*Track, error) {
-	conn, err := connect(s.address)
-	defer func() {
-		if err := conn.Close(); err != nil {
-			log.Warnf("unable to close connection to lms server after track parsing: %v", err)
-		}
-	}()
-	if err != nil {
-		return nil, fmt.Errorf("unable to connect to '%v' server: %v", s.address, err)
-	}
-	defer func() {
-		err := conn.Close()
-		if err != nil {
-			log.Warnf("unable to close connection to '%v' server: %v", s.address, err)
-		}
-	}()
-
-	lms := bufio.NewReader(conn)
-	t := Track{}
-
-	currentTitle, err := s.CurrentTitle(conn, lms, id)
-	if err != nil {
-		log.Warnf("unable to read current_title field: %v", err)
-	}
-
-	var mp MetadataParser
-	if strings.Index(currentTitle, "fip") == 0 || strings.Index(currentTitle, "FIP") == 0 {
-		mp = RadioFranceParser{}
-	} else {
-		mp = DefaultParser{}
-	}
-
-	artist, err := mp.Artist(conn, lms, id)
-	if err != nil {
-		log.Warnf("unable to read artist field: %v", err)
-	} else {
-		t.Artist = artist
-	}
-
-	album, err := mp.Album(conn, lms, id)
-	if err != nil {
-		log.Warnf("unable to read album field: %v", err)
-	} else {
-		t.Album = album
-	}
-
-	title, err := mp.Title(conn, lms, id)
-	if err != nil {
-		log.Warnf("unable to read title field: %v", err)
-	} else {
-		t.Title = title
-	}
+// PlayerTrack is the current track of a player, a synchronized player plays the track of its group Leader
+type PlayerTrack struct {
+	Player PlayerId
+	State  PlaybackState
+	Leader PlayerId `json:",omitempty"`
+	Track
+}
 
-	genre, err := mp.Genre(conn, lms, id)
+func (s *Server) CurrentTrack(id PlayerId) (*Track, error) {
+	status, err := s.Status(id)
 	if err != nil {
-		log.Warnf("unable to read genre field: %v", err)
-	} else {
-		t.Genre = genre
+		return nil, fmt.Errorf("unable to read status of player %v: %v", id, err)
 	}
+	return s.track(status), nil
+}
 
-	year, err := mp.Year(conn, lms, id)
+func (s *Server) onNewMetadata(line string) {
+	id := parsePlayerId(line)
+	t, err := s.playerTrack(id)
 	if err != nil {
-		log.Warnf("invalid year field: %v", err)
-	} else if year > 0 {
-		t.Year = year
+		log.Errorf("unable to extract current track metadata for player %v: %v", id, err)
+		return
 	}
+	s.notifyTrack(t)
+}
 
-	d, err := mp.Duration(conn, lms, id)
+// playerTrack read the current track and refresh the playback state from the same status
+func (s *Server) playerTrack(id PlayerId) (*PlayerTrack, error) {
+	status, err := s.Status(id)
 	if err != nil {
-		log.Warnf("unable to read duration field: %v", err)
-	} else if d != NilTrackDuration {
-		t.Duration = float64(d)
+		return nil, fmt.Errorf("unable to read status of player %v: %v", id, err)
 	}
-
-	tm, err := mp.Time(conn, lms, id)
-	if err != nil {
-		log.Warnf("unable to read time field: %v", err)
-	} else if tm != NilTrackTime {
-		t.CurrentTime = float64(tm)
+	state := playbackState(status.Mode)
+	if state != UnknownState {
+		s.setPlaybackState(id, state)
 	}
-
-	return &t, nil
+	t := s.track(status)
+	return &PlayerTrack{Player: id, State: s.PlaybackState(id), Leader: s.SyncLeader(id), Track: *t}, nil
 }
 
-func (s *Server) onNewMetadata(line string) {
-	args := strings.Split(strings.Trim(line, "\n"), " ")
-	id := PlayerId(args[0])
-	t, err := s.CurrentTrack(id)
-	if err != nil {
-		log.Errorf("unable to extract current track metadata for player %v: %v", id, err)
+func (s *Server) notifyTrack(t *PlayerTrack) {
+	select {
+	case s.chanNotify <- t:
+	case <-s.done:
 	}
-	s.chanNotify <- t
 }
 
 var connect = func(address string) (io.ReadWriteCloser, error) {
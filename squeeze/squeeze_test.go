@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -116,6 +117,30 @@ func TestServer_CurrentTrack(t *testing.T) {
 	}
 }
 
+func TestServer_processNewSongEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetRawTrack(RawTrack{rawCurrentTitle: "other", rawArtist: "Little%20Richard", rawYear: "1956"})
+
+	server := New(squeezeMock.Addr())
+	go server.processEventLine("00%3A04%3A20%3A12%3A34%3A56 playlist newsong Lucille 3\n")
+
+	track := <-server.NotifyTrackChange()
+	if track.Player != "00:04:20:12:34:56" {
+		t.Errorf("bad player: %#v, wants %#v", track.Player, "00:04:20:12:34:56")
+	}
+	if track.Artist != "Little Richard" {
+		t.Errorf("bad artist: %#v, wants %#v", track.Artist, "Little Richard")
+	}
+	if track.Year != 1956 {
+		t.Errorf("bad year: %#v, wants %#v", track.Year, 1956)
+	}
+}
+
 type RawTrack struct {
 	rawCurrentTitle string
 	rawArtist       string
@@ -131,6 +156,20 @@ type ConnMock struct {
 	muTrack sync.Mutex
 	track   RawTrack
 
+	rawVolume string
+	rawMuting string
+	rawMode   string
+	responses map[string]string
+	login     string
+	// dropLogins is the number of next logins closed as by a server restart
+	dropLogins int
+
+	muCommands sync.Mutex
+	commands   []string
+
+	muConns sync.Mutex
+	conns   []net.Conn
+
 	ln net.Listener
 }
 
@@ -149,6 +188,9 @@ func (c *ConnMock) listen() error {
 				log.Infof("connection close: %v", err)
 				break
 			}
+			c.muConns.Lock()
+			c.conns = append(c.conns, conn)
+			c.muConns.Unlock()
 			go c.handleConnection(conn)
 		}
 	}()
@@ -165,24 +207,88 @@ func (c *ConnMock) SetRawTrack(track RawTrack) {
 	c.track = track
 }
 
+// SetLogin require a 'login' command with the raw credentials as first command of each connection
+func (c *ConnMock) SetLogin(rawUsername, rawPassword string) {
+	c.muTrack.Lock()
+	defer c.muTrack.Unlock()
+	c.login = fmt.Sprintf("login %v %v", rawUsername, rawPassword)
+}
+
+// DropLogins close the connection of the next n logins, whatever the credentials
+func (c *ConnMock) DropLogins(n int) {
+	c.muTrack.Lock()
+	defer c.muTrack.Unlock()
+	c.dropLogins = n
+}
+
+func (c *ConnMock) checkLogin(reader *bufio.Reader, writer *bufio.Writer) bool {
+	c.muTrack.Lock()
+	expected := c.login
+	drop := c.dropLogins > 0
+	if drop {
+		c.dropLogins--
+	}
+	c.muTrack.Unlock()
+	if expected == "" {
+		return true
+	}
+
+	rawCmd, err := reader.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	c.recordCommand(rawCmd)
+	if drop || strings.TrimRight(rawCmd, "\r\n") != expected {
+		return false
+	}
+	fields := strings.Split(expected, " ")
+	_, err = writer.WriteString(fields[0] + " " + fields[1] + " ******\r\n")
+	if err == nil {
+		err = writer.Flush()
+	}
+	return err == nil
+}
+
 func (c *ConnMock) handleConnection(conn net.Conn) {
+	defer conn.Close()
 	reader := bufio.NewReader(conn)
 	writer := bufio.NewWriter(conn)
+	if !c.checkLogin(reader, writer) {
+		return
+	}
 	for {
 
 		rawCmd, err := reader.ReadString('\n')
 		if err != nil {
 			if err == io.EOF {
 				log.Info("connection closed")
-				break
+			} else {
+				log.Infof("unable to read request: %v", err)
 			}
-			log.Errorf("unable to read request: %v", err)
+			break
+		}
+		c.recordCommand(rawCmd)
+		if response, ok := c.response(rawCmd); ok {
+			// An empty response simulates a slow command which is never answered, the server
+			// processes the commands of a connection in order
+			if response == "" {
+				time.Sleep(200 * time.Millisecond)
+				continue
+			}
+			_, err = writer.WriteString(response + "\r\n")
+			if err == nil {
+				err = writer.Flush()
+			}
+			if err != nil {
+				log.Errorf("unable to write response: %v", err)
+			}
+			continue
 		}
 		args := strings.Split(rawCmd, " ")
 		player := PlayerId(args[0])
 		action := args[1]
 
-		err = c.writeResponse(action, writer, player)
+		err = c.writeResponse(action, args[2:], writer, player)
 		if err != nil {
 			log.Errorf("unable to write response: %v", err)
 		}
@@ -193,28 +299,81 @@ func (c *ConnMock) handleConnection(conn net.Conn) {
 	}
 }
 
-func (c *ConnMock) writeResponse(action string, writer *bufio.Writer, player PlayerId) error {
+func (c *ConnMock) SetRawMixer(rawVolume, rawMuting string) {
+	c.muTrack.Lock()
+	defer c.muTrack.Unlock()
+	c.rawVolume = rawVolume
+	c.rawMuting = rawMuting
+}
+
+// SetResponse register the raw response to write when the exact command is received, nothing is written
+// when the response is empty
+func (c *ConnMock) SetResponse(cmd, response string) {
+	c.muTrack.Lock()
+	defer c.muTrack.Unlock()
+	if c.responses == nil {
+		c.responses = make(map[string]string)
+	}
+	c.responses[cmd] = response
+}
+
+func (c *ConnMock) response(rawCmd string) (string, bool) {
+	c.muTrack.Lock()
+	defer c.muTrack.Unlock()
+	response, ok := c.responses[strings.TrimRight(rawCmd, "\r\n")]
+	return response, ok
+}
+
+func (c *ConnMock) SetRawMode(rawMode string) {
+	c.muTrack.Lock()
+	defer c.muTrack.Unlock()
+	c.rawMode = rawMode
+}
+
+func (c *ConnMock) recordCommand(rawCmd string) {
+	c.muCommands.Lock()
+	defer c.muCommands.Unlock()
+	c.commands = append(c.commands, strings.TrimRight(rawCmd, "\r\n"))
+}
+
+func (c *ConnMock) Commands() []string {
+	c.muCommands.Lock()
+	defer c.muCommands.Unlock()
+	return append([]string{}, c.commands...)
+}
+
+func (c *ConnMock) ResetCommands() {
+	c.muCommands.Lock()
+	defer c.muCommands.Unlock()
+	c.commands = nil
+}
+
+func (c *ConnMock) writeResponse(action string, args []string, writer *bufio.Writer, player PlayerId) error {
 	c.muTrack.Lock()
 	defer c.muTrack.Unlock()
 	log.Debugf("action: %v", action)
 	var err error
 	switch action {
-	case "artist":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawArtist))
-	case "current_title":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawCurrentTitle))
-	case "album":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawAlbum))
-	case "title":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawTitle))
-	case "genre":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawGenre))
-	case "year":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawYear))
-	case "time":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawCurrentTime))
-	case "duration":
-		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.track.rawDuration))
+	case "status":
+		t := c.track
+		_, err = writer.WriteString(fmt.Sprintf("%v %v - 1 tags%%3A%v mode%%3A%v current_title%%3A%v time%%3A%v duration%%3A%v "+
+			"playlist%%20index%%3A0 title%%3A%v artist%%3A%v album%%3A%v genre%%3A%v year%%3A%v duration%%3A%v\r\n",
+			player, action, statusTags, c.rawMode, t.rawCurrentTitle, t.rawCurrentTime, t.rawDuration,
+			t.rawTitle, t.rawArtist, t.rawAlbum, t.rawGenre, t.rawYear, t.rawDuration))
+	case "mode":
+		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, c.rawMode))
+	case "mixer":
+		var name, value string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		switch name {
+		case "volume":
+			value = c.rawVolume
+		case "muting":
+			value = c.rawMuting
+		}
+		_, err = writer.WriteString(fmt.Sprintf("%v %v %v %v\r\n", player, action, name, value))
 	default:
 		_, err = writer.WriteString(fmt.Sprintf("%v %v %v\r\n", player, action, ""))
 	}
@@ -224,6 +383,18 @@ func (c *ConnMock) writeResponse(action string, writer *bufio.Writer, player Pla
 	return writer.Flush()
 }
 
+// DropConnections close all opened client connections as a server restart would do
+func (c *ConnMock) DropConnections() {
+	c.muConns.Lock()
+	defer c.muConns.Unlock()
+	for _, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			log.Debugf("unable to close connection: %v", err)
+		}
+	}
+	c.conns = nil
+}
+
 func (c *ConnMock) Close() error {
 	log.Debug("close mock server")
 	err := c.ln.Close()
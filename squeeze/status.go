@@ -0,0 +1,122 @@
+package squeeze
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// statusTags request artist, album, genre, duration, year, track number, artwork, cover id, url, remote title, type,
+// rating and bitrate of the current track
+const statusTags = "aAlgdytKcuNoIRr"
+
+// Status is the state of a player and its current track, as returned by '<playerid> status - 1 tags:...'
+type Status struct {
+	Player         PlayerId
+	PlayerName     string
+	Connected      bool
+	Power          bool
+	Mode           string
+	Remote         bool
+	CurrentTitle   string
+	Time           TrackTime
+	Volume         int
+	PlaylistIndex  int
+	PlaylistTracks int
+
+	TrackId     string
+	Title       string
+	Artist      string
+	Album       string
+	Genre       string
+	Year        int
+	TrackNum    int
+	Duration    TrackDuration
+	CoverId     string
+	ArtworkUrl  string
+	Url         string
+	Bitrate     string
+	RemoteTitle string
+	Type        string
+
+	// Fields are the raw player fields and TrackFields the raw fields of the current track
+	Fields      map[string]string
+	TrackFields map[string]string
+}
+
+func (s *Server) Status(id PlayerId) (*Status, error) {
+	values, err := s.query(id, "status", "-", "1", "tags:"+statusTags)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch status: %v", err)
+	}
+	return decodeStatus(id, values), nil
+}
+
+// decodeStatus read the tagged fields of a status response, fields after 'playlist index' describe the current track
+func decodeStatus(id PlayerId, values []string) *Status {
+	// Skip the echoed '<playerid> status - 1' fields
+	if len(values) > 4 {
+		values = values[4:]
+	}
+	fields, items := parseTaggedResponse(values, "playlist index")
+	track := make(map[string]string)
+	if len(items) > 0 {
+		track = items[0]
+	}
+	return newStatus(id, fields, track)
+}
+
+// newStatus build the status of a player from its fields and the fields of a track of its playlist
+func newStatus(id PlayerId, fields, track map[string]string) *Status {
+	st := Status{
+		Player:         id,
+		PlayerName:     fields["player_name"],
+		Connected:      fields["player_connected"] == "1",
+		Power:          fields["power"] == "1",
+		Mode:           fields["mode"],
+		Remote:         fields["remote"] == "1",
+		CurrentTitle:   fields["current_title"],
+		Time:           TrackTime(parseFloat(fields["time"], float64(NilTrackTime))),
+		Volume:         int(parseFloat(fields["mixer volume"], 0)),
+		PlaylistIndex:  parseInt(fields["playlist_cur_index"]),
+		PlaylistTracks: parseInt(fields["playlist_tracks"]),
+		TrackId:        track["id"],
+		Title:          track["title"],
+		Artist:         track["artist"],
+		Album:          track["album"],
+		Genre:          track["genre"],
+		Year:           parseInt(track["year"]),
+		TrackNum:       parseInt(track["tracknum"]),
+		Duration:       NilTrackDuration,
+		CoverId:        track["coverid"],
+		ArtworkUrl:     track["artwork_url"],
+		Url:            track["url"],
+		Bitrate:        track["bitrate"],
+		RemoteTitle:    track["remote_title"],
+		Type:           track["type"],
+		Fields:         fields,
+		TrackFields:    track,
+	}
+	if d, ok := track["duration"]; ok {
+		st.Duration = TrackDuration(parseFloat(d, float64(NilTrackDuration)))
+	} else if d, ok := fields["duration"]; ok {
+		st.Duration = TrackDuration(parseFloat(d, float64(NilTrackDuration)))
+	}
+	return &st
+}
+
+// parseInt return 0 for unknown values like '?' or empty fields
+func parseInt(value string) int {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func parseFloat(value string, defaultValue float64) float64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package squeeze
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const rawStatus = "00%3A04%3A20%3A12%3A34%3A56 status - 1 tags%3AaAlgdytKcuNoIRr player_name%3AKitchen " +
+	"player_connected%3A1 player_ip%3A192.168.1.10%3A39872 power%3A1 signalstrength%3A0 mode%3Aplay remote%3A1 " +
+	"current_title%3AFIP time%3A23.5 rate%3A1 duration%3A240 can_seek%3A1 mixer%20volume%3A50 playlist%20repeat%3A0 " +
+	"playlist%20shuffle%3A0 playlist%20mode%3Aoff seq_no%3A0 playlist_cur_index%3A2 playlist_tracks%3A5 " +
+	"playlist%20index%3A2 id%3A-94371624 title%3AIn%20A%20Sentimental%20Mood artist%3ATenderlonious " +
+	"album%3AOn%20flute%20%2F%202019 genre%3AJazz year%3A%3F duration%3A241.5 tracknum%3A3 coverid%3A-94371624 " +
+	"artwork_url%3Ahttps%3A%2F%2Fexample.com%2Fcover.jpg url%3Ahttp%3A%2F%2Ficecast.radiofrance.fr%2Ffip-midfi.mp3 " +
+	"bitrate%3A128kb%2Fs remote_title%3AFIP type%3AMP3%20Radio"
+
+func Test_decodeStatus(t *testing.T) {
+	values, err := parseResponse(rawStatus)
+	if err != nil {
+		t.Fatalf("unable to parse response: %v", err)
+	}
+
+	st := decodeStatus("00:04:20:12:34:56", values)
+
+	expected := Status{
+		Player:         "00:04:20:12:34:56",
+		PlayerName:     "Kitchen",
+		Connected:      true,
+		Power:          true,
+		Mode:           "play",
+		Remote:         true,
+		CurrentTitle:   "FIP",
+		Time:           23.5,
+		Volume:         50,
+		PlaylistIndex:  2,
+		PlaylistTracks: 5,
+		TrackId:        "-94371624",
+		Title:          "In A Sentimental Mood",
+		Artist:         "Tenderlonious",
+		Album:          "On flute / 2019",
+		Genre:          "Jazz",
+		Year:           0,
+		TrackNum:       3,
+		Duration:       241.5,
+		CoverId:        "-94371624",
+		ArtworkUrl:     "https://example.com/cover.jpg",
+		Url:            "http://icecast.radiofrance.fr/fip-midfi.mp3",
+		Bitrate:        "128kb/s",
+		RemoteTitle:    "FIP",
+		Type:           "MP3 Radio",
+	}
+	st.Fields, st.TrackFields = nil, nil
+	if !reflect.DeepEqual(*st, expected) {
+		t.Errorf("bad status: %#v, wants %#v", *st, expected)
+	}
+}
+
+func Test_decodeStatusWithoutTrack(t *testing.T) {
+	values, err := parseResponse("00%3A04%3A20%3A12%3A34%3A56 status - 1 tags%3Aal player_name%3AKitchen mode%3Astop")
+	if err != nil {
+		t.Fatalf("unable to parse response: %v", err)
+	}
+
+	st := decodeStatus("00:04:20:12:34:56", values)
+	if st.Mode != "stop" || st.Title != "" {
+		t.Errorf("bad status: %#v", *st)
+	}
+	if st.Time != NilTrackTime || st.Duration != NilTrackDuration {
+		t.Errorf("time and duration should be undefined: %v, %v", st.Time, st.Duration)
+	}
+}
+
+func TestServer_Status(t *testing.T) {
+	squeezeMock := ConnMock{}
+	err := squeezeMock.listen()
+	if err != nil {
+		t.Errorf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("playerId status - 1 tags:"+statusTags,
+		strings.Replace(rawStatus, "00%3A04%3A20%3A12%3A34%3A56", string(playerId), 1))
+
+	server := New(squeezeMock.Addr())
+	st, err := server.Status(playerId)
+	if err != nil {
+		t.Fatalf("unable to read status: %v", err)
+	}
+	if st.Player != playerId || st.Artist != "Tenderlonious" || st.Duration != 241.5 {
+		t.Errorf("bad status: %#v", *st)
+	}
+	if commands := squeezeMock.Commands(); len(commands) != 1 {
+		t.Errorf("a single request is expected: %#v", commands)
+	}
+}
@@ -0,0 +1,122 @@
+package squeeze
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// SyncGroup is a group of synchronized players, the leader is the master whose playlist is played by the group
+type SyncGroup struct {
+	Leader  PlayerId
+	Members []PlayerId
+	Names   []string
+}
+
+// SyncGroups list the groups of synchronized players, lms lists the master first in each group
+func (s *Server) SyncGroups() ([]SyncGroup, error) {
+	values, err := s.query("", "syncgroups", "?")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list sync groups: %v", err)
+	}
+
+	groups := make([]SyncGroup, 0)
+	for _, item := range parseTaggedItems(values, "sync_members") {
+		if item["sync_members"] == "" {
+			continue
+		}
+		g := SyncGroup{Members: make([]PlayerId, 0)}
+		for _, m := range strings.Split(item["sync_members"], ",") {
+			g.Members = append(g.Members, PlayerId(m))
+		}
+		if names := item["sync_member_names"]; names != "" {
+			g.Names = strings.Split(names, ",")
+		}
+		g.Leader = g.Members[0]
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// Sync join the player to the group of leader
+func (s *Server) Sync(id PlayerId, leader PlayerId) error {
+	return s.Command(leader, "sync", string(id))
+}
+
+// Unsync remove the player from its group
+func (s *Server) Unsync(id PlayerId) error {
+	return s.Command(id, "sync", "-")
+}
+
+// SyncLeader return the leader of the player group, it is empty when the player isn't synchronized
+func (s *Server) SyncLeader(id PlayerId) PlayerId {
+	s.muPlayers.Lock()
+	defer s.muPlayers.Unlock()
+	return s.leaders[id]
+}
+
+func (s *Server) NotifySyncChange() <-chan []SyncGroup {
+	return s.chanSync
+}
+
+// isSyncEvent return true for '<playerid> sync <other>' and '<playerid> playlist sync' events
+func isSyncEvent(fields []string) bool {
+	if len(fields) > 2 && fields[1] == "sync" {
+		return true
+	}
+	return len(fields) > 2 && fields[1] == "playlist" && fields[2] == "sync"
+}
+
+// refreshSyncGroups notify the sync groups and the track of players whose leader changed
+func (s *Server) refreshSyncGroups() {
+	for _, id := range s.updateSyncGroups() {
+		if p, ok := s.Player(id); !ok || !p.Connected {
+			continue
+		}
+		t, err := s.playerTrack(id)
+		if err != nil {
+			log.Errorf("unable to refresh track of player %v: %v", id, err)
+			continue
+		}
+		s.notifyTrack(t)
+	}
+}
+
+// updateSyncGroups read and notify the sync groups, it returns the players whose leader changed
+func (s *Server) updateSyncGroups() []PlayerId {
+	groups, err := s.SyncGroups()
+	if err != nil {
+		log.Errorf("unable to read sync groups: %v", err)
+		return nil
+	}
+	leaders := make(map[PlayerId]PlayerId)
+	for _, g := range groups {
+		for _, m := range g.Members {
+			leaders[m] = g.Leader
+		}
+	}
+
+	s.muPlayers.Lock()
+	changed := make([]PlayerId, 0)
+	for id, leader := range leaders {
+		if s.leaders[id] != leader {
+			changed = append(changed, id)
+		}
+	}
+	for id := range s.leaders {
+		if _, ok := leaders[id]; !ok {
+			changed = append(changed, id)
+		}
+	}
+	s.leaders = leaders
+	s.muPlayers.Unlock()
+	sort.Slice(changed, func(i, j int) bool { return changed[i] < changed[j] })
+
+	select {
+	case s.chanSync <- groups:
+	case <-s.done:
+		return nil
+	}
+	return changed
+}
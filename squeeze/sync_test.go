@@ -0,0 +1,100 @@
+package squeeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+const rawSyncGroups = "syncgroups sync_members%3A00%3A04%3A20%3A00%3A00%3A01%2CplayerId " +
+	"sync_member_names%3ALiving%2CKitchen sync_members%3A00%3A04%3A20%3A00%3A00%3A02%2C00%3A04%3A20%3A00%3A00%3A03 " +
+	"sync_member_names%3AOffice%2CBedroom"
+
+func TestServer_SyncGroups(t *testing.T) {
+	cases := []struct {
+		name           string
+		response       string
+		expectedGroups []SyncGroup
+	}{
+		{"Groups", rawSyncGroups, []SyncGroup{
+			{Leader: "00:04:20:00:00:01", Members: []PlayerId{"00:04:20:00:00:01", "playerId"}, Names: []string{"Living", "Kitchen"}},
+			{Leader: "00:04:20:00:00:02", Members: []PlayerId{"00:04:20:00:00:02", "00:04:20:00:00:03"}, Names: []string{"Office", "Bedroom"}},
+		}},
+		{"No group", "syncgroups", []SyncGroup{}},
+	}
+
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	for _, c := range cases {
+		squeezeMock.SetResponse("syncgroups ?", c.response)
+		groups, err := server.SyncGroups()
+		if err != nil {
+			t.Errorf("[%v] unable to list sync groups: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(groups, c.expectedGroups) {
+			t.Errorf("[%v] bad groups: %#v, wants %#v", c.name, groups, c.expectedGroups)
+		}
+	}
+}
+
+func TestServer_SyncCommands(t *testing.T) {
+	cases := []struct {
+		name            string
+		command         func(s *Server) error
+		expectedCommand string
+	}{
+		{"Sync", func(s *Server) error { return s.Sync(playerId, "00:04:20:00:00:01") }, "00:04:20:00:00:01 sync playerId"},
+		{"Unsync", func(s *Server) error { return s.Unsync(playerId) }, "playerId sync -"},
+	}
+
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	server := New(squeezeMock.Addr())
+
+	for _, c := range cases {
+		squeezeMock.ResetCommands()
+		if err := c.command(server); err != nil {
+			t.Errorf("[%v] unable to send command: %v", c.name, err)
+		}
+		if commands := squeezeMock.Commands(); len(commands) != 1 || commands[0] != c.expectedCommand {
+			t.Errorf("[%v] bad commands: %#v, wants %#v", c.name, commands, c.expectedCommand)
+		}
+	}
+}
+
+func TestServer_onSyncEvent(t *testing.T) {
+	squeezeMock := ConnMock{}
+	if err := squeezeMock.listen(); err != nil {
+		t.Fatalf("unable to start mock squeeze server: %v", err)
+	}
+	defer squeezeMock.Close()
+	squeezeMock.SetResponse("syncgroups ?", rawSyncGroups)
+
+	server := New(squeezeMock.Addr())
+	server.players[playerId] = &Player{Id: playerId, Connected: true}
+	go server.processEventLine("00%3A04%3A20%3A00%3A00%3A01 sync playerId\n")
+
+	groups := <-server.NotifySyncChange()
+	if len(groups) != 2 {
+		t.Errorf("bad groups: %#v", groups)
+	}
+	// Only the connected players whose leader changed are refreshed
+	track := <-server.NotifyTrackChange()
+	if track.Player != playerId || track.Leader != "00:04:20:00:00:01" {
+		t.Errorf("bad track: %#v", *track)
+	}
+	if leader := server.SyncLeader("00:04:20:00:00:03"); leader != "00:04:20:00:00:02" {
+		t.Errorf("bad leader: %v", leader)
+	}
+	if leader := server.SyncLeader("00:04:20:00:00:04"); leader != "" {
+		t.Errorf("unsynced player shouldn't have a leader: %v", leader)
+	}
+}
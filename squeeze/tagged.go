@@ -0,0 +1,40 @@
+package squeeze
+
+import (
+	"strings"
+)
+
+// parseTaggedItems group the unescaped 'key:value' fields of a response by item,
+// a new item starts each time firstKey is read. Fields before the first item are ignored.
+func parseTaggedItems(fields []string, firstKey string) []map[string]string {
+	_, items := parseTaggedResponse(fields, firstKey)
+	return items
+}
+
+// parseTaggedResponse split the unescaped 'key:value' fields of a response between the
+// fields read before the first item and the items, a new item starts each time firstKey is read.
+func parseTaggedResponse(fields []string, firstKey string) (map[string]string, []map[string]string) {
+	head := make(map[string]string)
+	items := make([]map[string]string, 0)
+	current := head
+	for _, field := range fields {
+		key, value, ok := splitTag(field)
+		if !ok {
+			continue
+		}
+		if key == firstKey {
+			current = make(map[string]string)
+			items = append(items, current)
+		}
+		current[key] = value
+	}
+	return head, items
+}
+
+func splitTag(field string) (string, string, bool) {
+	i := strings.Index(field, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	return field[:i], field[i+1:], true
+}
@@ -0,0 +1,48 @@
+package squeeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseTaggedItems(t *testing.T) {
+	cases := []struct {
+		name          string
+		fields        []string
+		expectedItems []map[string]string
+	}{
+		{"Empty", []string{"players", "0", "0", "count:0"}, []map[string]string{}},
+		{"Single item",
+			[]string{"players", "0", "1", "count:1", "playerindex:0", "playerid:00:04:20:12:34:56", "name:Kitchen"},
+			[]map[string]string{{"playerindex": "0", "playerid": "00:04:20:12:34:56", "name": "Kitchen"}},
+		},
+		{"Many items",
+			[]string{"players", "0", "2", "count:2", "playerindex:0", "name:Kitchen", "playerindex:1", "name:Living room", "other"},
+			[]map[string]string{{"playerindex": "0", "name": "Kitchen"}, {"playerindex": "1", "name": "Living room"}},
+		},
+		{"Empty value", []string{"playerindex:0", "name:"}, []map[string]string{{"playerindex": "0", "name": ""}}},
+	}
+
+	for _, c := range cases {
+		items := parseTaggedItems(c.fields, "playerindex")
+		if !reflect.DeepEqual(items, c.expectedItems) {
+			t.Errorf("[%v] bad items: %#v, wants %#v", c.name, items, c.expectedItems)
+		}
+	}
+}
+
+func Test_parseTaggedResponse(t *testing.T) {
+	fields := []string{"status", "-", "1", "tags:al", "mode:play", "time:12.5",
+		"playlist index:0", "title:Lucille", "artist:Little Richard"}
+
+	head, items := parseTaggedResponse(fields, "playlist index")
+
+	expectedHead := map[string]string{"tags": "al", "mode": "play", "time": "12.5"}
+	if !reflect.DeepEqual(head, expectedHead) {
+		t.Errorf("bad head fields: %#v, wants %#v", head, expectedHead)
+	}
+	expectedItems := []map[string]string{{"playlist index": "0", "title": "Lucille", "artist": "Little Richard"}}
+	if !reflect.DeepEqual(items, expectedItems) {
+		t.Errorf("bad items: %#v, wants %#v", items, expectedItems)
+	}
+}
@@ -0,0 +1,114 @@
+package squeeze
+
+import (
+	"reflect"
+	"time"
+)
+
+// statusTracker notify the changes between successive server and player status, for event sources which read
+// the whole status on each update, as cometd subscriptions.
+// It is only used by the listening goroutine.
+type statusTracker struct {
+	s *Server
+
+	tracks    map[PlayerId]PlayerTrack
+	mixers    map[PlayerId]Mixer
+	playlists map[PlayerId]string
+	syncs     map[PlayerId]string
+	alarms    map[PlayerId]alarmStatus
+	sleeps    map[PlayerId]string
+}
+
+// alarmStatus are the alarm fields of a player status
+type alarmStatus struct {
+	state string
+	next  string
+}
+
+func newStatusTracker(s *Server) *statusTracker {
+	return &statusTracker{
+		s:         s,
+		tracks:    make(map[PlayerId]PlayerTrack),
+		mixers:    make(map[PlayerId]Mixer),
+		playlists: make(map[PlayerId]string),
+		syncs:     make(map[PlayerId]string),
+		alarms:    make(map[PlayerId]alarmStatus),
+		sleeps:    make(map[PlayerId]string),
+	}
+}
+
+// onServerStatus update the players with the fields of a 'serverstatus' response and return them
+func (c *statusTracker) onServerStatus(values []string) []Player {
+	s := c.s
+	before := s.Players()
+	s.setPlayers(parseTaggedItems(values, "playerindex"))
+	players := s.Players()
+	if !reflect.DeepEqual(before, players) {
+		select {
+		case s.chanPlayers <- players:
+		case <-s.done:
+		}
+	}
+	return players
+}
+
+// onPlayerStatus notify the changes of the fields of a '<playerid> status' response
+func (c *statusTracker) onPlayerStatus(id PlayerId, values []string) {
+	s := c.s
+	status := decodeStatus(id, values)
+	s.setPower(id, status.Power)
+	state := playbackState(status.Mode)
+	if state != UnknownState {
+		s.setPlaybackState(id, state)
+	}
+
+	// Sync groups changes are read before the track to publish it with the new leader
+	group := status.Fields["sync_master"] + "/" + status.Fields["sync_slaves"]
+	if last, ok := c.syncs[id]; ok && last != group {
+		s.refreshSyncGroups()
+	}
+	c.syncs[id] = group
+
+	// The track is notified again when the playback state changes, elapsed time changes at each update
+	// and isn't a track change
+	t := &PlayerTrack{Player: id, State: s.PlaybackState(id), Leader: s.SyncLeader(id), Track: *s.track(status)}
+	track := *t
+	track.CurrentTime = 0
+	if last, ok := c.tracks[id]; !ok || last != track {
+		c.tracks[id] = track
+		s.notifyTrack(t)
+	}
+
+	// Status has no muting flag, a muted player only reports a negative volume
+	m := newMixer(id, parseFloat(status.Fields["mixer volume"], 0), false)
+	if last, ok := c.mixers[id]; !ok || last != *m {
+		c.mixers[id] = *m
+		s.notifyMixer(m)
+	}
+
+	// The playlist timestamp changes with the queue content
+	playlist := status.Fields["playlist_timestamp"] + "/" + status.Fields["playlist_cur_index"]
+	if last, ok := c.playlists[id]; !ok || last != playlist {
+		c.playlists[id] = playlist
+		s.refreshPlaylist(id)
+	}
+
+	// Alarm events aren't pushed, they are deduced from the alarm state and the alarms are read again
+	// when the next alarm changes
+	alarm := alarmStatus{state: status.Fields["alarm_state"], next: status.Fields["alarm_next"]}
+	if last, ok := c.alarms[id]; ok {
+		if e := alarmStateEvent(last.state, alarm.state); e != "" {
+			s.notifyAlarmEvent(&AlarmEvent{Player: id, Event: e})
+		}
+		if last.next != alarm.next {
+			s.refreshAlarms(id)
+		}
+	}
+	c.alarms[id] = alarm
+
+	// 'sleep' is the duration of the timer, 'will_sleep_in' the remaining time which changes at each update
+	if last, ok := c.sleeps[id]; !ok || last != status.Fields["sleep"] {
+		c.sleeps[id] = status.Fields["sleep"]
+		s.notifySleep(newSleep(id, parseFloat(status.Fields["will_sleep_in"], 0), time.Now()))
+	}
+}
@@ -0,0 +1,15 @@
+package squeeze
+
+// transport send requests to the server and return the unescaped fields of their responses,
+// responses of every transport follow the cli format: echoed request then 'key:value' fields
+type transport interface {
+	query(requests ...request) ([][]string, error)
+	setCredentials(username, password string)
+	Close() error
+}
+
+// request is a cli command, player is empty for server wide requests
+type request struct {
+	player PlayerId
+	args   []string
+}